@@ -3,15 +3,451 @@ package sql
 import (
 	"context"
 	"fmt"
+	"github.com/hashicorp/golang-lru/v2"
 	"github.com/jitsucom/bulker/base/coordination"
+	"github.com/jitsucom/bulker/base/logging"
 	"github.com/jitsucom/bulker/bulker"
+	"github.com/jitsucom/bulker/implementations/sql/schemaregistry"
 	"github.com/jitsucom/bulker/types"
+	"time"
 )
 
 // TODO: tableHelper not sure that using cache is always applicable. Transaction rollback may lead to inconsistency.
 // TODO: check whether COPY is transactional ?
 // TODO: pk conflict on Redshift file storage ?
 
+// InsertStats summarizes the outcome of a single Insert call, passed to Hooks.AfterInsert.
+type InsertStats struct {
+	Objects int
+}
+
+// Hooks are optional lifecycle callbacks an operator can attach to a stream via WithHooks to scrub,
+// enrich, or audit data flowing through an adapter without forking it. A hook that returns an error
+// aborts the operation and is surfaced through the same errorj wrapping the adapter already uses;
+// a hook that returns a modified objects slice or table replaces the value flowing downstream.
+type Hooks struct {
+	BeforeInsert      func(ctx context.Context, table *Table, objects []types.Object) ([]types.Object, error)
+	AfterInsert       func(ctx context.Context, table *Table, stats InsertStats)
+	BeforeCopyTables  func(ctx context.Context, targetTable, sourceTable *Table) error
+	AfterCopyTables   func(ctx context.Context, targetTable, sourceTable *Table)
+	BeforeLoadTable   func(ctx context.Context, targetTable *Table, loadSource *LoadSource) error
+	AfterLoadTable    func(ctx context.Context, targetTable *Table)
+	BeforeCreateTable func(ctx context.Context, table *Table) (*Table, error)
+	OnError           func(ctx context.Context, phase string, err error)
+}
+
+var hooksOption = bulker.Option[Hooks]{Key: "hooks"}
+
+// WithHooks attaches Hooks to a stream, alongside the existing WithColumnTypes stream option. Adapters
+// read the active Hooks off the context via HooksFromContext at each Insert/CopyTables/LoadTable/CreateTable call.
+func WithHooks(hooks Hooks) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		hooksOption.Set(options, hooks)
+	}
+}
+
+// SchemaRegistryRawField is the reserved types.Object key an event carries its raw Confluent wire-format
+// payload under (0x00 magic byte + 4-byte schema id + Avro/JSON body, see schemaregistry.DecodeWireHeader).
+// Producers that enable WithSchemaRegistry must stamp incoming objects under this key; preprocess reads it
+// to resolve the schema id before falling back to per-event ProcessEvents inference.
+const SchemaRegistryRawField = "__schema_registry_raw"
+
+// schemaRegistryConfig is WithSchemaRegistry's resolved state: a client against the registry plus the
+// subject incoming events are published under.
+type schemaRegistryConfig struct {
+	client  *schemaregistry.Client
+	subject string
+}
+
+var schemaRegistryOption = bulker.Option[*schemaRegistryConfig]{Key: "schemaRegistry"}
+
+// WithSchemaRegistry points a stream at a Confluent Schema Registry so events carrying a
+// SchemaRegistryRawField payload can skip per-event ProcessEvents/MapTableSchema inference once their
+// schema id has been seen: preprocess caches the resulting *Table keyed by schema id and reuses it for
+// every subsequent event sharing that schema, producing zero additional DDL calls for a stable upstream
+// schema. url is the registry's base URL (e.g. "http://localhost:8081"); subject scopes the schema cache.
+func WithSchemaRegistry(url, subject string) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		client, err := schemaregistry.NewClient(url)
+		if err != nil {
+			return
+		}
+		schemaRegistryOption.Set(options, &schemaRegistryConfig{client: client, subject: subject})
+	}
+}
+
+// SystemColumnsConfig names the audit columns WithSystemColumns manages automatically. A blank field
+// leaves the corresponding column unmanaged, so callers opt into only the ones they need.
+type SystemColumnsConfig struct {
+	CreatedAt  string
+	UpdatedAt  string
+	IngestedAt string
+	BatchID    string
+}
+
+// hasAny reports whether any system column is configured.
+func (c SystemColumnsConfig) hasAny() bool {
+	return c.CreatedAt != "" || c.UpdatedAt != "" || c.IngestedAt != "" || c.BatchID != ""
+}
+
+// dataTypes returns the types.DataType each configured system column should resolve to through the
+// adapter's GetTypesMapping(): a timezone-aware timestamp for the three audit timestamps, and a plain
+// string for the batch id.
+func (c SystemColumnsConfig) dataTypes() map[string]types.DataType {
+	dt := make(map[string]types.DataType, 4)
+	if c.CreatedAt != "" {
+		dt[c.CreatedAt] = types.TIMESTAMPTZ
+	}
+	if c.UpdatedAt != "" {
+		dt[c.UpdatedAt] = types.TIMESTAMPTZ
+	}
+	if c.IngestedAt != "" {
+		dt[c.IngestedAt] = types.TIMESTAMPTZ
+	}
+	if c.BatchID != "" {
+		dt[c.BatchID] = types.STRING
+	}
+	return dt
+}
+
+var systemColumnsOption = bulker.Option[SystemColumnsConfig]{Key: "systemColumns"}
+
+// WithSystemColumns attaches SystemColumnsConfig to a stream, alongside WithColumnTypes and WithHooks.
+// The stream stamps every row with the configured columns before Insert/LoadTable (CreatedAt and
+// UpdatedAt with the current time, IngestedAt with the time the stream was opened, BatchID with the
+// stream's id) and forces their SQL type regardless of any WithColumnTypes entry for the same name, so
+// callers no longer have to reimplement the same audit columns through Hooks.
+func WithSystemColumns(cfg SystemColumnsConfig) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		systemColumnsOption.Set(options, cfg)
+	}
+}
+
+type systemColumnsContextKey struct{}
+
+// ctxWithSystemColumns threads a stream's SystemColumnsConfig onto ctx so SQLAdapterBase's merge logic
+// can exclude CreatedAt from the ON CONFLICT UPDATE SET, leaving the original creation time in place.
+func ctxWithSystemColumns(ctx context.Context, cfg SystemColumnsConfig) context.Context {
+	return context.WithValue(ctx, systemColumnsContextKey{}, cfg)
+}
+
+// SystemColumnsFromContext returns the SystemColumnsConfig attached to ctx by ctxWithSystemColumns, or
+// the zero value (no managed columns) if none was attached.
+func SystemColumnsFromContext(ctx context.Context) SystemColumnsConfig {
+	cfg, _ := ctx.Value(systemColumnsContextKey{}).(SystemColumnsConfig)
+	return cfg
+}
+
+var streamingInsertOption = bulker.Option[bool]{Key: "streamingInsert"}
+
+// WithStreamingInsert opts an AutoCommit stream into an adapter's legacy low-latency streaming insert
+// path, where the adapter has one, instead of its default batch-oriented Insert. BigQuery is the only
+// adapter that currently distinguishes the two: its default batch load job has no per-request row cap
+// and (unlike the streaming API) doesn't leave rows invisible to DML for up to 90 minutes, so this is
+// opt-in only for callers that specifically need streaming's lower per-row latency.
+func WithStreamingInsert() bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		streamingInsertOption.Set(options, true)
+	}
+}
+
+var batchSizeOption = bulker.Option[int]{Key: "batchSize"}
+
+// WithBatchSize sets how many rows ClickHouse's native batch insert path (PrepareBatch/Append/Send)
+// buffers per batch.Send() call during Insert/LoadTable, instead of the adapter's built-in default. Only
+// meaningful for ClickHouse's native (non-HTTP) DSNs; other adapters ignore it.
+func WithBatchSize(n int) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		batchSizeOption.Set(options, n)
+	}
+}
+
+var storagePoolSizeOption = bulker.Option[int]{Key: "storagePoolSize"}
+
+// WithStoragePoolSize asks an adapter's object-storage-backed bulk modes (BigQuery's GCS staging today;
+// Redshift/Snowflake would read the same option if this snapshot carried those adapters) to shard a
+// batch across n storage clients and upload the shards concurrently instead of staging one object at a
+// time. The pool itself is built lazily on the adapter the first time a stream requests one and is then
+// shared by every later stream on that adapter, not rebuilt per stream. n <= 1 disables pooling.
+func WithStoragePoolSize(n int) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		storagePoolSizeOption.Set(options, n)
+	}
+}
+
+type storagePoolSizeContextKey struct{}
+
+// ctxWithStoragePoolSize threads a stream's WithStoragePoolSize choice onto ctx so the adapter's staging
+// code can read it without otherwise seeing the stream's options.
+func ctxWithStoragePoolSize(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, storagePoolSizeContextKey{}, n)
+}
+
+// StoragePoolSizeFromContext returns the pool size attached to ctx by WithStoragePoolSize, or 0 (pooling
+// disabled) if none was set.
+func StoragePoolSizeFromContext(ctx context.Context) int {
+	n, _ := ctx.Value(storagePoolSizeContextKey{}).(int)
+	return n
+}
+
+// checkpointConfig is WithCheckpointing's resolved state: where to persist Checkpoints and how often.
+type checkpointConfig struct {
+	store    CheckpointStore
+	interval time.Duration
+}
+
+var checkpointingOption = bulker.Option[*checkpointConfig]{Key: "checkpointing"}
+
+// WithCheckpointing persists a Checkpoint to store at most once per interval as postConsume advances,
+// recording {streamId, tableName, batchId, lastOffset, processedRows} so a crashed loader can resume by
+// calling ResumeStream(ctx, store, ...) with the same streamId instead of restarting the stream from
+// scratch. See ResumeStream (checkpoint.go) for the load side of this.
+func WithCheckpointing(store CheckpointStore, interval time.Duration) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		checkpointingOption.Set(options, &checkpointConfig{store: store, interval: interval})
+	}
+}
+
+var tableMappingCacheSizeOption = bulker.Option[int]{Key: "tableMappingCacheSize"}
+
+// WithTableMappingCacheSize overrides how many mapped *Table results preprocess keeps cached by field
+// signature (see tableMappingCache) before evicting the least recently used; 0 or unset falls back to
+// tableMappingCacheSize.
+func WithTableMappingCacheSize(n int) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		tableMappingCacheSizeOption.Set(options, n)
+	}
+}
+
+type streamingInsertContextKey struct{}
+
+// ctxWithStreamingInsert threads a stream's WithStreamingInsert choice onto ctx so Insert can read it
+// without otherwise seeing the stream's options.
+func ctxWithStreamingInsert(ctx context.Context, streamingInsert bool) context.Context {
+	return context.WithValue(ctx, streamingInsertContextKey{}, streamingInsert)
+}
+
+// StreamingInsertFromContext reports whether the stream was opened with WithStreamingInsert.
+func StreamingInsertFromContext(ctx context.Context) bool {
+	v, _ := ctx.Value(streamingInsertContextKey{}).(bool)
+	return v
+}
+
+type batchSizeContextKey struct{}
+
+// ctxWithBatchSize threads a stream's WithBatchSize choice onto ctx so Insert/LoadTable can read it
+// without otherwise seeing the stream's options.
+func ctxWithBatchSize(ctx context.Context, batchSize int) context.Context {
+	return context.WithValue(ctx, batchSizeContextKey{}, batchSize)
+}
+
+// BatchSizeFromContext returns the batch size attached to ctx by WithBatchSize, or 0 if none was set.
+func BatchSizeFromContext(ctx context.Context) int {
+	v, _ := ctx.Value(batchSizeContextKey{}).(int)
+	return v
+}
+
+type batchIDContextKey struct{}
+
+// ctxWithBatchID threads a stream's id (which doubles as its batch id, see AbstractSQLStream.batchID)
+// onto ctx so Insert/LoadTable can derive a per-batch value, e.g. ClickHouse's
+// insert_deduplication_token, without otherwise seeing the stream.
+func ctxWithBatchID(ctx context.Context, batchID string) context.Context {
+	return context.WithValue(ctx, batchIDContextKey{}, batchID)
+}
+
+// BatchIDFromContext returns the batch id attached to ctx by ctxWithBatchID, or "" if none was attached.
+func BatchIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(batchIDContextKey{}).(string)
+	return v
+}
+
+// TablePhysicalOptions tunes physical table properties that WithClusteringFields, WithPartitionExpiration,
+// WithRequirePartitionFilter and WithTableExpiration attach to a stream. Each adapter's CreateTable applies
+// whichever of these it supports and ignores the rest; today only BigQuery supports any of them.
+type TablePhysicalOptions struct {
+	ClusteringFields       []string
+	PartitionExpiration    time.Duration
+	RequirePartitionFilter bool
+	TableExpiration        time.Time
+	PartitionKey           string
+	PartitionRangeStart    time.Time
+	PartitionRangeEnd      time.Time
+}
+
+var tablePhysicalOptionsOption = bulker.Option[TablePhysicalOptions]{Key: "tablePhysicalOptions"}
+
+// WithClusteringFields sets up to 4 columns (BigQuery's limit) to cluster new tables by, giving large
+// customers a cost win on their common filter/join columns.
+func WithClusteringFields(fields ...string) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		opts := tablePhysicalOptionsOption.Get(options)
+		opts.ClusteringFields = fields
+		tablePhysicalOptionsOption.Set(options, opts)
+	}
+}
+
+// WithPartitionExpiration sets how long a partition is retained after its partition time, for adapters
+// whose CreateTable supports partition expiration.
+func WithPartitionExpiration(d time.Duration) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		opts := tablePhysicalOptionsOption.Get(options)
+		opts.PartitionExpiration = d
+		tablePhysicalOptionsOption.Set(options, opts)
+	}
+}
+
+// WithRequirePartitionFilter requires queries against new tables to include a partition filter,
+// preventing runaway full-scan queries against multi-TB event tables.
+func WithRequirePartitionFilter(require bool) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		opts := tablePhysicalOptionsOption.Get(options)
+		opts.RequirePartitionFilter = require
+		tablePhysicalOptionsOption.Set(options, opts)
+	}
+}
+
+// WithTableExpiration sets a time after which new tables are automatically deleted.
+func WithTableExpiration(t time.Time) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		opts := tablePhysicalOptionsOption.Get(options)
+		opts.TableExpiration = t
+		tablePhysicalOptionsOption.Set(options, opts)
+	}
+}
+
+// WithPartitionRange sets a literal partition-key predicate (column name plus inclusive range) that
+// Select/Delete/Update inject directly into the generated SQL instead of binding it as a query
+// parameter, since BigQuery (and similarly partition-pruning engines) can only prune scanned bytes on
+// a literal. Required on adapters/datasets that reject unfiltered queries via require_partition_filter.
+func WithPartitionRange(key string, start, end time.Time) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		opts := tablePhysicalOptionsOption.Get(options)
+		opts.PartitionKey = key
+		opts.PartitionRangeStart = start
+		opts.PartitionRangeEnd = end
+		tablePhysicalOptionsOption.Set(options, opts)
+	}
+}
+
+type tablePhysicalOptionsContextKey struct{}
+
+// ctxWithTablePhysicalOptions threads a stream's TablePhysicalOptions onto ctx so CreateTable can read
+// them without otherwise seeing the stream's options.
+func ctxWithTablePhysicalOptions(ctx context.Context, opts TablePhysicalOptions) context.Context {
+	return context.WithValue(ctx, tablePhysicalOptionsContextKey{}, opts)
+}
+
+// TablePhysicalOptionsFromContext returns the TablePhysicalOptions attached to ctx by
+// ctxWithTablePhysicalOptions, or the zero value (no physical tuning) if none were attached.
+func TablePhysicalOptionsFromContext(ctx context.Context) TablePhysicalOptions {
+	opts, _ := ctx.Value(tablePhysicalOptionsContextKey{}).(TablePhysicalOptions)
+	return opts
+}
+
+// DeduplicationMode selects how CopyTables upserts a batch onto a target with declared primary keys.
+// The default, DeduplicationMerge, issues a MERGE (or, lacking a PK, a staging-table dedup) query;
+// DeduplicationNone always does a plain append, skipping that slot-time cost even when PK fields are
+// declared, for destinations whose upstream pipeline already guarantees no duplicates.
+type DeduplicationMode string
+
+const (
+	DeduplicationMerge DeduplicationMode = "merge"
+	DeduplicationNone  DeduplicationMode = "none"
+)
+
+var deduplicationModeOption = bulker.Option[DeduplicationMode]{Key: "deduplicationMode"}
+
+// WithDeduplicationMode overrides a stream's CopyTables deduplication strategy. Omitting it keeps the
+// default, DeduplicationMerge.
+func WithDeduplicationMode(mode DeduplicationMode) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		deduplicationModeOption.Set(options, mode)
+	}
+}
+
+type deduplicationModeContextKey struct{}
+
+// ctxWithDeduplicationMode threads a stream's DeduplicationMode onto ctx so CopyTables can read it
+// without otherwise seeing the stream's options.
+func ctxWithDeduplicationMode(ctx context.Context, mode DeduplicationMode) context.Context {
+	return context.WithValue(ctx, deduplicationModeContextKey{}, mode)
+}
+
+// DeduplicationModeFromContext returns the DeduplicationMode attached to ctx by
+// ctxWithDeduplicationMode, defaulting to DeduplicationMerge if none (or the zero value) was attached.
+func DeduplicationModeFromContext(ctx context.Context) DeduplicationMode {
+	mode, _ := ctx.Value(deduplicationModeContextKey{}).(DeduplicationMode)
+	if mode == "" {
+		return DeduplicationMerge
+	}
+	return mode
+}
+
+var storageWriteAPIOption = bulker.Option[bool]{Key: "bigqueryStorageWriteAPI"}
+
+// WithStorageWriteAPI opts a stream into BigQuery's Storage Write API (managedwriter, default stream,
+// at-least-once semantics) for Insert instead of the legacy streaming Inserter or the default batch
+// load job. Unlike the legacy Inserter it has no 500-row-per-request cap and no 90-minute
+// invisible-to-DML buffer; unlike the batch load path it's true row-at-a-time streaming. Falls back to
+// the adapter's usual Insert path if unset.
+func WithStorageWriteAPI() bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		storageWriteAPIOption.Set(options, true)
+	}
+}
+
+type storageWriteAPIContextKey struct{}
+
+// ctxWithStorageWriteAPI threads a stream's WithStorageWriteAPI choice onto ctx so Insert can read it
+// without otherwise seeing the stream's options.
+func ctxWithStorageWriteAPI(ctx context.Context, useStorageWriteAPI bool) context.Context {
+	return context.WithValue(ctx, storageWriteAPIContextKey{}, useStorageWriteAPI)
+}
+
+// StorageWriteAPIFromContext reports whether the stream was opened with WithStorageWriteAPI.
+func StorageWriteAPIFromContext(ctx context.Context) bool {
+	v, _ := ctx.Value(storageWriteAPIContextKey{}).(bool)
+	return v
+}
+
+type hooksContextKey struct{}
+
+// ctxWithHooks threads a stream's Hooks onto ctx so adapter methods (which don't otherwise see the
+// stream's options) can look them up via HooksFromContext.
+func ctxWithHooks(ctx context.Context, hooks Hooks) context.Context {
+	return context.WithValue(ctx, hooksContextKey{}, hooks)
+}
+
+// HooksFromContext returns the Hooks attached to ctx by ctxWithHooks, or the zero value (no-op hooks)
+// if none were attached.
+func HooksFromContext(ctx context.Context) Hooks {
+	hooks, _ := ctx.Value(hooksContextKey{}).(Hooks)
+	return hooks
+}
+
+// runOnError invokes hooks.OnError, if set, without altering err.
+func runOnError(ctx context.Context, hooks Hooks, phase string, err error) {
+	if hooks.OnError != nil && err != nil {
+		hooks.OnError(ctx, phase, err)
+	}
+}
+
+type modeContextKey struct{}
+
+// ctxWithMode threads a stream's bulk mode onto ctx so adapter methods can tell whether retrying a
+// given call is idempotent (e.g. AutoCommit/ReplaceTable land a retried batch in a table that's
+// truncated or swapped in afterwards, so duplicate rows from a retried write never surface).
+func ctxWithMode(ctx context.Context, mode bulker.BulkMode) context.Context {
+	return context.WithValue(ctx, modeContextKey{}, mode)
+}
+
+// ModeFromContext returns the bulk mode attached to ctx by ctxWithMode, or the zero value if none was attached.
+func ModeFromContext(ctx context.Context) bulker.BulkMode {
+	mode, _ := ctx.Value(modeContextKey{}).(bulker.BulkMode)
+	return mode
+}
+
 type AbstractSQLStream struct {
 	id         string
 	sqlAdapter SQLAdapter
@@ -19,6 +455,39 @@ type AbstractSQLStream struct {
 	options    bulker.StreamOptions
 	tableName  string
 	merge      bool
+	hooks      Hooks
+
+	// systemColumns, ingestedAt and batchID back WithSystemColumns. A stream corresponds to exactly one
+	// Complete() call, so its id doubles as the batch id stamped on every row.
+	systemColumns        SystemColumnsConfig
+	ingestedAt           time.Time
+	batchID              string
+	streamingInsert      bool
+	tablePhysicalOptions TablePhysicalOptions
+	deduplicationMode    DeduplicationMode
+	storageWriteAPI      bool
+	batchSize            int
+	storagePoolSize      int
+
+	// schemaRegistry and schemaCache back WithSchemaRegistry: schemaCache holds the *Table MapTableSchema
+	// produced the first time a given schema id was seen, keyed by that id, so repeat events sharing a
+	// schema skip ProcessEvents/MapTableSchema entirely (see preprocess).
+	schemaRegistry *schemaRegistryConfig
+	schemaCache    *lru.Cache[int, *Table]
+
+	// tableMapping caches MapTableSchema results by field signature across the life of this stream; see
+	// tableMappingCache for what it does and doesn't cover.
+	tableMapping *tableMappingCache
+
+	// checkpointing and lastCheckpointAt back WithCheckpointing: lastCheckpointAt gates how often
+	// postConsume actually writes to checkpointing.store.
+	checkpointing    *checkpointConfig
+	lastCheckpointAt time.Time
+
+	// dupDetection, dupSeen and duplicates back WithDuplicateDetection; see detectDuplicate/FlushDuplicates.
+	dupDetection *dupDetectionConfig
+	dupSeen      *dupSeenSet
+	duplicates   []DuplicateRow
 
 	state       bulker.State
 	tableHelper *TableHelper
@@ -35,30 +504,182 @@ func newAbstractStream(id string, p SQLAdapter, tx TxOrDB, tableName string, mod
 	if ps.merge && len(primaryKeyOption.Get(&ps.options)) == 0 {
 		return AbstractSQLStream{}, fmt.Errorf("MergeRows option requires primary key in the destination table. Please provide WithPrimaryKey option")
 	}
+	ps.hooks = hooksOption.Get(&ps.options)
+	ps.systemColumns = systemColumnsOption.Get(&ps.options)
+	ps.ingestedAt = time.Now().UTC()
+	ps.batchID = id
+	ps.streamingInsert = streamingInsertOption.Get(&ps.options)
+	ps.tablePhysicalOptions = tablePhysicalOptionsOption.Get(&ps.options)
+	ps.deduplicationMode = deduplicationModeOption.Get(&ps.options)
+	ps.storageWriteAPI = storageWriteAPIOption.Get(&ps.options)
+	ps.batchSize = batchSizeOption.Get(&ps.options)
+	ps.storagePoolSize = storagePoolSizeOption.Get(&ps.options)
+	ps.schemaRegistry = schemaRegistryOption.Get(&ps.options)
+	if ps.schemaRegistry != nil {
+		ps.schemaCache, _ = lru.New[int, *Table](256)
+	}
+	ps.tableMapping = newTableMappingCache(tableMappingCacheSizeOption.Get(&ps.options))
+	ps.checkpointing = checkpointingOption.Get(&ps.options)
+	ps.dupDetection = dupDetectionOption.Get(&ps.options)
 	//TODO: max column?
 	ps.tableHelper = NewTableHelper(p, tx, coordination.DummyCoordinationService{}, primaryKeyOption.Get(&ps.options), 1000)
 	ps.state = bulker.State{Status: bulker.Active}
 	return ps, nil
 }
 
+// ctx returns ctx with ps.hooks, ps.mode, ps.systemColumns, ps.streamingInsert,
+// ps.tablePhysicalOptions, ps.deduplicationMode, ps.storagePoolSize and ps.batchID attached so the
+// underlying sqlAdapter can dispatch Before/After hooks, tell whether a retry is idempotent, exclude
+// CreatedAt from the merge UPDATE SET, pick its Insert strategy, tune new tables' physical properties,
+// pick its CopyTables strategy, size its object-storage upload pool and derive a per-batch insert
+// deduplication token around Insert, CopyTables, LoadTable and CreateTable.
+func (ps *AbstractSQLStream) ctx(ctx context.Context) context.Context {
+	ctx = ctxWithSystemColumns(ctxWithMode(ctxWithHooks(ctx, ps.hooks), ps.mode), ps.systemColumns)
+	ctx = ctxWithStreamingInsert(ctx, ps.streamingInsert)
+	ctx = ctxWithTablePhysicalOptions(ctx, ps.tablePhysicalOptions)
+	ctx = ctxWithDeduplicationMode(ctx, ps.deduplicationMode)
+	ctx = ctxWithBatchSize(ctx, ps.batchSize)
+	ctx = ctxWithStoragePoolSize(ctx, ps.storagePoolSize)
+	ctx = ctxWithBatchID(ctx, ps.batchID)
+	return ctxWithStorageWriteAPI(ctx, ps.storageWriteAPI)
+}
+
+// applySystemColumns stamps object with the columns configured via WithSystemColumns. CreatedAt is
+// stamped on every row here too; it only takes effect on genuinely new rows because the merge template
+// excludes it from the UPDATE SET (see ctxWithSystemColumns), leaving ON CONFLICT rows' original value alone.
+func (ps *AbstractSQLStream) applySystemColumns(object types.Object) {
+	sc := ps.systemColumns
+	if !sc.hasAny() {
+		return
+	}
+	now := time.Now().UTC()
+	if sc.CreatedAt != "" {
+		object[sc.CreatedAt] = now
+	}
+	if sc.UpdatedAt != "" {
+		object[sc.UpdatedAt] = now
+	}
+	if sc.IngestedAt != "" {
+		object[sc.IngestedAt] = ps.ingestedAt
+	}
+	if sc.BatchID != "" {
+		object[sc.BatchID] = ps.batchID
+	}
+}
+
 func (ps *AbstractSQLStream) preprocess(object types.Object) (*Table, []types.Object, error) {
 	if ps.state.Status != bulker.Active {
 		return nil, nil, fmt.Errorf("stream is not active. Status: %s", ps.state.Status)
 	}
+	ps.applySystemColumns(object)
+	ps.detectDuplicate(object)
+
+	if ps.schemaRegistry != nil {
+		if table, processedObjects, ok, err := ps.preprocessWithSchemaRegistry(object); ok || err != nil {
+			if err == nil {
+				ps.state.ProcessedRows++
+			}
+			return table, processedObjects, err
+		}
+	}
+
+	var customFields = columnTypesOption.Get(&ps.options)
+	if sc := ps.systemColumns; sc.hasAny() {
+		mapping := ps.sqlAdapter.GetTypesMapping()
+		for name, dataType := range sc.dataTypes() {
+			if sqlType, ok := mapping[dataType]; ok {
+				if customFields == nil {
+					customFields = SQLTypes{}
+				}
+				// force our own type for system columns, overriding any WithColumnTypes entry for the same name
+				customFields = customFields.With(name, sqlType)
+			}
+		}
+	}
+
+	// The signature only depends on object's field names/value types and customFields, neither of which
+	// ProcessEvents computes, so it can be checked before paying for ProcessEvents' own type-inference work.
+	// On a hit, object is used as-is for processedObjects, same as preprocessWithSchemaRegistry's cache-hit
+	// path above — ProcessEvents is skipped entirely rather than just the MapTableSchema call.
+	signature := tableMappingSignature(ps.tableName, object, customFields)
+	if table, cached := ps.tableMapping.Get(signature); cached {
+		if ps.schemaRegistry != nil {
+			ps.cacheSchemaRegistryTable(object, table)
+		}
+		ps.state.ProcessedRows++
+		return table, []types.Object{object}, nil
+	}
+
 	batchHeader, processedObjects, err := ProcessEvents(ps.tableName, []types.Object{object})
 	if err != nil {
 		return nil, nil, err
 	}
-	var customFields = columnTypesOption.Get(&ps.options)
 	if len(customFields) > 0 {
 		// enrich overridden schema types
 		batchHeader.Fields.OverrideTypes(customFields)
 	}
 	table := ps.tableHelper.MapTableSchema(batchHeader)
+	ps.tableMapping.Add(signature, table)
+	if ps.schemaRegistry != nil {
+		ps.cacheSchemaRegistryTable(object, table)
+	}
 	ps.state.ProcessedRows++
 	return table, processedObjects, nil
 }
 
+// schemaIDFromObject extracts the Confluent schema id embedded in object's SchemaRegistryRawField, if
+// present. ok is false when the field is absent, letting preprocess fall back to the regular
+// ProcessEvents/MapTableSchema path unchanged (e.g. for events that don't originate from a registry-backed
+// source even though the stream has WithSchemaRegistry configured).
+func schemaIDFromObject(object types.Object) (schemaID int, ok bool) {
+	raw, present := object[SchemaRegistryRawField]
+	if !present {
+		return 0, false
+	}
+	payload, isBytes := raw.([]byte)
+	if !isBytes {
+		return 0, false
+	}
+	id, _, err := schemaregistry.DecodeWireHeader(payload)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// preprocessWithSchemaRegistry is preprocess's fast path for events carrying a SchemaRegistryRawField
+// payload: on a schema id cache hit it returns the cached *Table directly, bypassing ProcessEvents and
+// MapTableSchema entirely; on a miss it looks the schema up (to produce registry traffic exactly once per
+// new schema id) and lets the caller fall through to the regular inference path, which populates the
+// cache for the next event sharing this schema. ok is false whenever the caller should run the regular
+// path itself (no SchemaRegistryRawField on object, or nothing cached yet).
+func (ps *AbstractSQLStream) preprocessWithSchemaRegistry(object types.Object) (table *Table, processedObjects []types.Object, ok bool, err error) {
+	schemaID, present := schemaIDFromObject(object)
+	if !present {
+		return nil, nil, false, nil
+	}
+	if cached, hit := ps.schemaCache.Get(schemaID); hit {
+		return cached, []types.Object{object}, true, nil
+	}
+	// First sighting of this schema id: touch the registry so Lookup's own cache records it, then fall
+	// through to the regular ProcessEvents/MapTableSchema path, which this schema id's result is cached
+	// against in cacheSchemaRegistryTable. The lookup is best-effort: it exists only to warm the registry
+	// client's cache, and its result isn't otherwise used (the *Table still comes from ProcessEvents/
+	// MapTableSchema), so a registry outage must not fail an event that would otherwise ingest fine.
+	if _, lookupErr := ps.schemaRegistry.client.Lookup(context.Background(), ps.schemaRegistry.subject, schemaID); lookupErr != nil {
+		logging.Warnf("schema registry lookup failed for schema id %d: %v", schemaID, lookupErr)
+	}
+	return nil, nil, false, nil
+}
+
+// cacheSchemaRegistryTable stores table under object's Confluent schema id so later events sharing that
+// schema hit preprocessWithSchemaRegistry's fast path instead of re-running ProcessEvents/MapTableSchema.
+func (ps *AbstractSQLStream) cacheSchemaRegistryTable(object types.Object, table *Table) {
+	if schemaID, present := schemaIDFromObject(object); present {
+		ps.schemaCache.Add(schemaID, table)
+	}
+}
+
 func (ps *AbstractSQLStream) postConsume(err error) error {
 	if err != nil {
 		ps.state.ErrorRowIndex = ps.state.ProcessedRows
@@ -67,10 +688,41 @@ func (ps *AbstractSQLStream) postConsume(err error) error {
 	} else {
 		ps.state.SuccessfulRows++
 	}
+	ps.checkpoint()
 	return nil
 }
 
+// checkpoint persists a Checkpoint to ps.checkpointing.store at most once per ps.checkpointing.interval,
+// so a long-running stream doesn't hit its coordination backend on every single row. No-ops when the
+// stream wasn't opened with WithCheckpointing.
+func (ps *AbstractSQLStream) checkpoint() {
+	cfg := ps.checkpointing
+	if cfg == nil || cfg.store == nil {
+		return
+	}
+	now := time.Now().UTC()
+	if !ps.lastCheckpointAt.IsZero() && now.Sub(ps.lastCheckpointAt) < cfg.interval {
+		return
+	}
+	ps.lastCheckpointAt = now
+	processedRows := int64(ps.state.ProcessedRows)
+	_ = cfg.store.SaveCheckpoint(context.Background(), Checkpoint{
+		StreamID:      ps.id,
+		TableName:     ps.tableName,
+		BatchID:       ps.batchID,
+		LastOffset:    processedRows,
+		ProcessedRows: processedRows,
+		UpdatedAt:     now,
+	})
+}
+
 func (ps *AbstractSQLStream) postComplete(err error) (bulker.State, error) {
+	// FlushDuplicates writes to an audit sidecar table, not the stream's actual target table: a failure
+	// there doesn't mean the load itself failed, so it's logged rather than folded into err/Status - an
+	// already-successful load must not be reported as bulker.Failed over a sidecar-write problem.
+	if flushErr := ps.FlushDuplicates(context.Background()); flushErr != nil {
+		logging.Warnf("stream %s: failed to flush duplicate-detection sidecar rows: %v", ps.id, flushErr)
+	}
 	if err != nil {
 		ps.state.LastError = err
 		ps.state.Status = bulker.Failed
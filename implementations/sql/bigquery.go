@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
@@ -13,15 +14,26 @@ import (
 	"github.com/jitsucom/bulker/implementations"
 	"github.com/jitsucom/bulker/types"
 	"google.golang.org/api/iterator"
+	"io"
 	"math"
+	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"cloud.google.com/go/storage"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 const (
@@ -32,25 +44,61 @@ const (
 	selectBigQueryTemplate   = "SELECT %s FROM `%s.%s.%s`%s"
 
 	rowsLimitPerInsertOperation = 500
+
+	// bigQueryLoadChunkBytes bounds how much gzip'd newline-delimited JSON Insert's batch-load path
+	// stages per GCS object/load job, so one oversized batch can't blow out a single job's memory.
+	bigQueryLoadChunkBytes = 250 * 1024 * 1024
+	bigQueryStageObjectDir = "bulker_insert"
+
+	// bigQueryLoadObjectDir is where LoadTable stages a batch file before loading it, kept separate
+	// from bigQueryStageObjectDir (Insert's own staging) so the two paths never collide on a name.
+	bigQueryLoadObjectDir = "bulker_load"
 )
 
 var (
-	//SchemaToBigQueryString is mapping between JSON types and BigQuery types
+	//SchemaToBigQueryString is mapping between JSON types and BigQuery types. ARRAY_STRING/ARRAY_INT64/
+	//ARRAY_FLOAT64 map to their element's scalar FieldType rather than a true BigQuery REPEATED column:
+	//CreateTable/PatchTableSchema in this adapter snapshot only ever set a FieldSchema's Type, never its
+	//Mode, so there's no column-level wiring yet to emit Mode: "REPEATED" for them. HSTORE has no BigQuery
+	//equivalent and is intentionally left unmapped.
 	SchemaToBigQueryString = map[types.DataType]string{
-		types.STRING:    string(bigquery.StringFieldType),
-		types.INT64:     string(bigquery.IntegerFieldType),
-		types.FLOAT64:   string(bigquery.FloatFieldType),
-		types.TIMESTAMP: string(bigquery.TimestampFieldType),
-		types.BOOL:      string(bigquery.BooleanFieldType),
-		types.UNKNOWN:   string(bigquery.StringFieldType),
+		types.STRING:        string(bigquery.StringFieldType),
+		types.INT64:         string(bigquery.IntegerFieldType),
+		types.FLOAT64:       string(bigquery.FloatFieldType),
+		types.TIMESTAMP:     string(bigquery.TimestampFieldType),
+		types.BOOL:          string(bigquery.BooleanFieldType),
+		types.UNKNOWN:       string(bigquery.StringFieldType),
+		types.JSON:          string(bigquery.JSONFieldType),
+		types.ARRAY_STRING:  string(bigquery.StringFieldType),
+		types.ARRAY_INT64:   string(bigquery.IntegerFieldType),
+		types.ARRAY_FLOAT64: string(bigquery.FloatFieldType),
+		types.UUID:          string(bigquery.StringFieldType),
+		types.DECIMAL:       string(bigquery.NumericFieldType),
+		types.TIMESTAMPTZ:   string(bigquery.TimestampFieldType),
 	}
 )
 
 // BigQuery adapter for creating,patching (schema or table), inserting and copying data from gcs to BigQuery
 type BigQuery struct {
-	client      *bigquery.Client
-	config      *implementations.GoogleConfig
-	queryLogger *logging.QueryLogger
+	client        *bigquery.Client
+	storageClient *storage.Client
+	config        *implementations.GoogleConfig
+	queryLogger   *logging.QueryLogger
+
+	// storageWriteClient is created lazily on first WithStorageWriteAPI Insert, not in NewBigquery: most
+	// streams never use it, and a managedwriter.Client opens its own gRPC connection pool.
+	storageWriteClient   *managedwriter.Client
+	storageWriteClientMu sync.Mutex
+
+	// gcsPool backs WithStoragePoolSize: built lazily on the first stream that requests pooling and
+	// shared by every stream on this adapter afterward (see storagePool).
+	gcsPool   *gcsClientPool
+	gcsPoolMu sync.Mutex
+
+	// schemaCache holds GetTableSchema results keyed by table name, shared across every stream this
+	// adapter instance opens; CreateTable and PatchTableSchema invalidate a table's entry whenever they
+	// change its shape. See table_schema_cache.go.
+	schemaCache *tableSchemaCache
 }
 
 // NewBigquery return configured BigQuery bulker.Bulker instance
@@ -76,7 +124,17 @@ func NewBigquery(bulkerConfig bulker.Config) (bulker.Bulker, error) {
 		return nil, fmt.Errorf("Error creating BigQuery client: %v", err)
 	}
 
-	return &BigQuery{client: client, config: config, queryLogger: logging.NewQueryLogger(bulkerConfig.Id, os.Stderr, os.Stderr)}, nil
+	var storageClient *storage.Client
+	if config.Credentials == nil {
+		storageClient, err = storage.NewClient(ctx)
+	} else {
+		storageClient, err = storage.NewClient(ctx, config.Credentials)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error creating BigQuery storage client: %v", err)
+	}
+
+	return &BigQuery{client: client, storageClient: storageClient, config: config, queryLogger: logging.NewQueryLogger(bulkerConfig.Id, os.Stderr, os.Stderr), schemaCache: newTableSchemaCache(0)}, nil
 }
 
 func (bq *BigQuery) CreateStream(id, tableName string, mode bulker.BulkMode, streamOptions ...bulker.StreamOption) (bulker.BulkerStream, error) {
@@ -93,8 +151,73 @@ func (bq *BigQuery) CreateStream(id, tableName string, mode bulker.BulkMode, str
 	return nil, fmt.Errorf("unsupported bulk mode: %s", mode)
 }
 
+// bqJobID returns a job ID stable across retries of one logical operation (kind plus a random
+// suffix), so if runJobWithRetry retries after an ambiguous failure, BigQuery recognizes the
+// duplicate request and returns the original job's status instead of running it twice.
+func bqJobID(kind string) string {
+	return fmt.Sprintf("bulker_%s_%d_%d", kind, time.Now().UnixNano(), rand.Int63())
+}
+
+// runJobWithRetry runs newJob and waits for it to finish, retrying transient BigQuery failures
+// (isRetryable) with withRetry's backoff. newJob must reuse the same JobID on every call (see
+// bqJobID) so a retried job creation is deduped against one that actually completed. idempotent
+// should be true only when replaying the underlying operation is safe.
+// runQueryJobWithRetry runs queryStr with retry on transient failures (rate limits, backend errors,
+// 503s), building a fresh query and a fresh JobID (via bqJobID) on every attempt. Unlike
+// runJobWithRetry's other callers, which reuse one JobID across attempts so BigQuery's own job-ID
+// dedup makes a retry safe, Select/Update/Delete have no state a stuck prior job could conflict with,
+// so a fresh JobID per attempt is simpler and sidesteps "job already exists" conflicts outright.
+func (bq *BigQuery) runQueryJobWithRetry(ctx context.Context, kind string, queryStr string, params []bigquery.QueryParameter) (*bigquery.Job, error) {
+	var job *bigquery.Job
+	err := withRetry(ctx, true, func(attempt int) error {
+		query := bq.client.Query(queryStr)
+		query.Parameters = params
+		query.JobID = bqJobID(kind)
+		j, runErr := query.Run(ctx)
+		if runErr != nil {
+			return runErr
+		}
+		status, waitErr := j.Wait(ctx)
+		if waitErr != nil {
+			return waitErr
+		}
+		if statusErr := status.Err(); statusErr != nil {
+			return statusErr
+		}
+		job = j
+		return nil
+	})
+	return job, err
+}
+
+func (bq *BigQuery) runJobWithRetry(ctx context.Context, idempotent bool, newJob func() (*bigquery.Job, error)) error {
+	return withRetry(ctx, idempotent, func(attempt int) error {
+		job, err := newJob()
+		if err != nil {
+			return err
+		}
+		status, err := job.Wait(ctx)
+		if err != nil {
+			return err
+		}
+		return status.Err()
+	})
+}
+
+// CopyTables copies sourceTable's rows into targetTable. With merge=false it's a plain append. With
+// merge=true it upserts on targetTable's primary key via a MERGE statement when one is declared;
+// otherwise (no declared PK) it falls back to deduping the staging table's exact-duplicate rows with
+// ROW_NUMBER before appending, so late-arriving retries of the same batch don't double up in the
+// target. WithDeduplicationMode(DeduplicationNone) opts a stream out of both and always appends.
 func (bq *BigQuery) CopyTables(ctx context.Context, targetTable *Table, sourceTable *Table, merge bool) (err error) {
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeCopyTables != nil {
+		if err = hooks.BeforeCopyTables(ctx, targetTable, sourceTable); err != nil {
+			return err
+		}
+	}
 	defer func() {
+		runOnError(ctx, hooks, "copyTables", err)
 		if err != nil {
 			err = errorj.CopyError.Wrap(err, "failed to run BQ copier").
 				WithProperty(errorj.DBInfo, &types.ErrorPayload{
@@ -103,41 +226,241 @@ func (bq *BigQuery) CopyTables(ctx context.Context, targetTable *Table, sourceTa
 					Project: bq.config.Project,
 					Table:   targetTable.Name,
 				})
+		} else if hooks.AfterCopyTables != nil {
+			hooks.AfterCopyTables(ctx, targetTable, sourceTable)
 		}
 	}()
+
+	// DeduplicationNone always appends, skipping the MERGE/dedup slot-time cost even with PK fields
+	// declared, for destinations whose upstream pipeline already guarantees no duplicates.
+	dedupe := merge && DeduplicationModeFromContext(ctx) != DeduplicationNone
+
+	pkFields := targetTable.GetPKFields()
+	if dedupe && len(pkFields) > 0 {
+		return bq.mergeCopyTables(ctx, targetTable, sourceTable, pkFields)
+	}
+
 	dataset := bq.client.Dataset(bq.config.Dataset)
+	sourceName := sourceTable.Name
+	if dedupe {
+		dedupedName, cleanup, dErr := bq.dedupStagingTable(ctx, sourceTable)
+		if dErr != nil {
+			return dErr
+		}
+		defer cleanup()
+		sourceName = dedupedName
+	}
 
-	copier := dataset.Table(targetTable.Name).CopierFrom(dataset.Table(sourceTable.Name))
+	copier := dataset.Table(targetTable.Name).CopierFrom(dataset.Table(sourceName))
 	copier.WriteDisposition = bigquery.WriteAppend
 	copier.CreateDisposition = bigquery.CreateIfNeeded
+	copier.JobID = bqJobID("copy_tables")
 
-	job, err := copier.Run(ctx)
-	if err != nil {
-		return err
+	return bq.runJobWithRetry(ctx, true, func() (*bigquery.Job, error) {
+		return copier.Run(ctx)
+	})
+}
+
+// mergeCopyTables upserts sourceTable's rows into targetTable on pkFields via a MERGE statement.
+// Identifiers are backtick-quoted and the statement carries no parameters: BigQuery DML referencing
+// table/column identifiers can't use query parameters for those positions.
+func (bq *BigQuery) mergeCopyTables(ctx context.Context, targetTable, sourceTable *Table, pkFields []string) error {
+	queryStr := bq.mergeCopyTablesQuery(targetTable, sourceTable, pkFields)
+	bq.logQuery("MERGE copy tables: ", queryStr)
+
+	query := bq.client.Query(queryStr)
+	query.JobID = bqJobID("merge_copy_tables")
+	return bq.runJobWithRetry(ctx, true, func() (*bigquery.Job, error) {
+		return query.Run(ctx)
+	})
+}
+
+// mergeCopyTablesQuery builds the MERGE statement mergeCopyTables and its DryRun counterpart run.
+func (bq *BigQuery) mergeCopyTablesQuery(targetTable, sourceTable *Table, pkFields []string) string {
+	isPK := make(map[string]bool, len(pkFields))
+	for _, pk := range pkFields {
+		isPK[pk] = true
 	}
-	jobStatus, err := job.Wait(ctx)
-	if err != nil {
+	columns := targetTable.SortedColumnNames()
+
+	onClauses := make([]string, len(pkFields))
+	for i, pk := range pkFields {
+		onClauses[i] = fmt.Sprintf("T.`%s` = S.`%s`", pk, pk)
+	}
+	var updateSet []string
+	insertColumns := make([]string, len(columns))
+	insertValues := make([]string, len(columns))
+	for i, column := range columns {
+		insertColumns[i] = "`" + column + "`"
+		insertValues[i] = "S.`" + column + "`"
+		if !isPK[column] {
+			updateSet = append(updateSet, fmt.Sprintf("T.`%s` = S.`%s`", column, column))
+		}
+	}
+
+	return fmt.Sprintf(
+		"MERGE INTO `%s.%s.%s` T USING `%s.%s.%s` S ON %s WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)",
+		bq.config.Project, bq.config.Dataset, targetTable.Name,
+		bq.config.Project, bq.config.Dataset, sourceTable.Name,
+		strings.Join(onClauses, " AND "),
+		strings.Join(updateSet, ", "),
+		strings.Join(insertColumns, ", "),
+		strings.Join(insertValues, ", "),
+	)
+}
+
+// dedupStagingTable writes sourceTable's distinct rows (by every column) to a new temp table and
+// returns its name plus a cleanup func to drop it. Used when CopyTables is asked to merge but
+// targetTable declares no primary key, so there's no natural key to MERGE on: a retried batch landing
+// twice in the staging table would otherwise be appended twice.
+func (bq *BigQuery) dedupStagingTable(ctx context.Context, sourceTable *Table) (string, func(), error) {
+	columns := sourceTable.SortedColumnNames()
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = "`" + column + "`"
+	}
+	orderBy := "(SELECT NULL)"
+	if sc := SystemColumnsFromContext(ctx); sc.UpdatedAt != "" {
+		orderBy = "`" + sc.UpdatedAt + "` DESC"
+	} else if sc.IngestedAt != "" {
+		orderBy = "`" + sc.IngestedAt + "` DESC"
+	}
+
+	dedupedName := sourceTable.Name + "_deduped_" + utils.SanitizeString(bq.config.Dataset)
+	// CREATE OR REPLACE, not CREATE, so a retried attempt (same JobID or not) never fails with
+	// "already exists" against a table a prior, ambiguously-failed attempt actually managed to create.
+	queryStr := fmt.Sprintf(
+		"CREATE OR REPLACE TABLE `%s.%s.%s` AS SELECT * EXCEPT(bulker_row_number) FROM "+
+			"(SELECT *, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) bulker_row_number FROM `%s.%s.%s`) WHERE bulker_row_number = 1",
+		bq.config.Project, bq.config.Dataset, dedupedName,
+		strings.Join(quoted, ", "), orderBy,
+		bq.config.Project, bq.config.Dataset, sourceTable.Name,
+	)
+	bq.logQuery("Dedup staging table before merge-append: ", queryStr)
+
+	query := bq.client.Query(queryStr)
+	query.JobID = bqJobID("dedup_staging_table")
+	if err := bq.runJobWithRetry(ctx, true, func() (*bigquery.Job, error) {
+		return query.Run(ctx)
+	}); err != nil {
+		return "", nil, err
+	}
+
+	cleanup := func() {
+		_ = bq.client.Dataset(bq.config.Dataset).Table(dedupedName).Delete(context.Background())
+	}
+	return dedupedName, cleanup, nil
+}
+
+// MergeInto loads loadSource directly into a throwaway tmp table via a batch load job, then upserts its
+// rows into targetTable on targetTable's primary key via the same MERGE statement CopyTables uses, and
+// finally drops the tmp table. This gives PK-deduped ingestion of a local/GCS file without ever routing
+// rows through the streaming Inserter, avoiding its 90-minute buffer and per-row insert quota. targetTable
+// must declare a primary key; callers without one should use LoadTable directly instead.
+func (bq *BigQuery) MergeInto(ctx context.Context, targetTable *Table, loadSource *LoadSource) (err error) {
+	pkFields := targetTable.GetPKFields()
+	if len(pkFields) == 0 {
+		return fmt.Errorf("MergeInto: targetTable %s has no primary key to merge on", targetTable.Name)
+	}
+
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeCopyTables != nil {
+		if err = hooks.BeforeCopyTables(ctx, targetTable, nil); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		runOnError(ctx, hooks, "mergeInto", err)
+		if err != nil {
+			err = errorj.CopyError.Wrap(err, "failed to merge into table").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset:     bq.config.Dataset,
+					Bucket:      bq.config.Bucket,
+					Project:     bq.config.Project,
+					Table:       targetTable.Name,
+					PrimaryKeys: pkFields,
+				})
+		} else if hooks.AfterCopyTables != nil {
+			hooks.AfterCopyTables(ctx, targetTable, nil)
+		}
+	}()
+
+	tmpTable := &Table{Name: batchStagingTableName(targetTable.Name, BatchIDFromContext(ctx)), Columns: targetTable.Columns}
+	if err := bq.CreateTable(ctx, tmpTable); err != nil {
 		return err
 	}
+	defer func() {
+		_ = bq.DropTable(context.Background(), tmpTable.Name, true)
+	}()
 
-	if jobStatus.Err() != nil {
-		return jobStatus.Err()
+	if err := bq.LoadTable(ctx, tmpTable, loadSource); err != nil {
+		return err
 	}
 
-	return nil
+	return bq.mergeCopyTables(ctx, targetTable, tmpTable, pkFields)
+}
+
+// CopySourceFormat names the GCS source format a batch load job should parse, mirroring the formats
+// bigquery.LoaderFrom already supports.
+type CopySourceFormat string
+
+const (
+	CopySourceFormatJSON    CopySourceFormat = "JSON"
+	CopySourceFormatCSV     CopySourceFormat = "CSV"
+	CopySourceFormatAvro    CopySourceFormat = "AVRO"
+	CopySourceFormatParquet CopySourceFormat = "PARQUET"
+	CopySourceFormatORC     CopySourceFormat = "ORC"
+)
+
+// CopyOptions tunes CopyWithOptions beyond Copy's JSON/CreateNever defaults, so callers can ingest
+// customer-provided GCS exports (Segment/Fivetran-style Parquet dumps, headered CSVs, Avro from
+// Dataflow) without a preprocessing step. Schema, when set, is used verbatim instead of letting
+// BigQuery infer one from the file, for formats/files that don't carry their own (e.g. headerless CSV).
+type CopyOptions struct {
+	SourceFormat        CopySourceFormat
+	Schema              *Table
+	SkipLeadingRows     int64
+	FieldDelimiter      string
+	AllowQuotedNewlines bool
+	AllowJaggedRows     bool
+	MaxBadRecords       int64
+	IgnoreUnknownValues bool
 }
 
-// Copy transfers data from google cloud storage file to google BigQuery table as one batch
+// Copy transfers data from google cloud storage file to google BigQuery table as one batch, assuming
+// newline-delimited JSON. See CopyWithOptions for other source formats and load tuning.
 func (bq *BigQuery) Copy(ctx context.Context, fileKey, tableName string) error {
+	return bq.CopyWithOptions(ctx, fileKey, tableName, CopyOptions{SourceFormat: CopySourceFormatJSON})
+}
+
+// CopyWithOptions transfers data from a google cloud storage file to a google BigQuery table as one
+// batch load job, per opts. See CopyOptions.
+func (bq *BigQuery) CopyWithOptions(ctx context.Context, fileKey, tableName string, opts CopyOptions) error {
 	table := bq.client.Dataset(bq.config.Dataset).Table(tableName)
 
 	gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", bq.config.Bucket, fileKey))
-	gcsRef.SourceFormat = bigquery.JSON
+	gcsRef.SourceFormat = bigquery.DataFormat(opts.SourceFormat)
+	gcsRef.SkipLeadingRows = opts.SkipLeadingRows
+	gcsRef.FieldDelimiter = opts.FieldDelimiter
+	gcsRef.AllowQuotedNewlines = opts.AllowQuotedNewlines
+	gcsRef.AllowJaggedRows = opts.AllowJaggedRows
+	gcsRef.MaxBadRecords = opts.MaxBadRecords
+	gcsRef.IgnoreUnknownValues = opts.IgnoreUnknownValues
+	if opts.Schema != nil {
+		bqSchema := bigquery.Schema{}
+		for _, columnName := range opts.Schema.SortedColumnNames() {
+			column := opts.Schema.Columns[columnName]
+			bqSchema = append(bqSchema, &bigquery.FieldSchema{Name: columnName, Type: bigquery.FieldType(strings.ToUpper(column.GetDDLType()))})
+		}
+		gcsRef.Schema = bqSchema
+	}
 	loader := table.LoaderFrom(gcsRef)
 	loader.CreateDisposition = bigquery.CreateNever
+	loader.JobID = bqJobID("copy_with_options")
 
-	job, err := loader.Run(ctx)
-	if err != nil {
+	if err := bq.runJobWithRetry(ctx, true, func() (*bigquery.Job, error) {
+		return loader.Run(ctx)
+	}); err != nil {
 		return errorj.CopyError.Wrap(err, "failed to run BQ loader").
 			WithProperty(errorj.DBInfo, &types.ErrorPayload{
 				Dataset: bq.config.Dataset,
@@ -146,41 +469,29 @@ func (bq *BigQuery) Copy(ctx context.Context, fileKey, tableName string) error {
 				Table:   tableName,
 			})
 	}
-	jobStatus, err := job.Wait(ctx)
-	if err != nil {
-		return errorj.CopyError.Wrap(err, "failed to wait BQ job").
-			WithProperty(errorj.DBInfo, &types.ErrorPayload{
-				Dataset: bq.config.Dataset,
-				Bucket:  bq.config.Bucket,
-				Project: bq.config.Project,
-				Table:   tableName,
-			})
-	}
-
-	if jobStatus.Err() != nil {
-		return errorj.CopyError.Wrap(jobStatus.Err(), "failed due to BQ job status").
-			WithProperty(errorj.DBInfo, &types.ErrorPayload{
-				Dataset: bq.config.Dataset,
-				Bucket:  bq.config.Bucket,
-				Project: bq.config.Project,
-				Table:   tableName,
-			})
-	}
 
 	return nil
 }
 
 func (bq *BigQuery) Test() error {
-	_, err := bq.client.Query("SELECT 1;").Read(context.Background())
-	return err
+	return withRetry(context.Background(), true, func(attempt int) error {
+		_, err := bq.client.Query("SELECT 1;").Read(context.Background())
+		return err
+	})
 }
 
 func (bq *BigQuery) GetTypesMapping() map[types.DataType]string {
 	return SchemaToBigQueryString
 }
 
-// GetTableSchema return google BigQuery table (name,columns) representation wrapped in Table struct
+// GetTableSchema return google BigQuery table (name,columns,primary key) representation wrapped in
+// Table struct. The primary key is read back from INFORMATION_SCHEMA, the native constraint
+// addPrimaryKeyConstraint declares in CreateTable/PatchTableSchema.
 func (bq *BigQuery) GetTableSchema(ctx context.Context, tableName string) (*Table, error) {
+	if cached, ok := bq.schemaCache.Get(tableName); ok {
+		return cached, nil
+	}
+
 	table := &Table{Name: tableName, Columns: Columns{}}
 
 	bqTable := bq.client.Dataset(bq.config.Dataset).Table(tableName)
@@ -204,14 +515,26 @@ func (bq *BigQuery) GetTableSchema(ctx context.Context, tableName string) (*Tabl
 		table.Columns[field.Name] = SQLColumn{Type: string(field.Type)}
 	}
 
+	pkFields, err := bq.getPrimaryKeyFields(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	table.PKFields = pkFields
+
+	bq.schemaCache.Put(tableName, table)
 	return table, nil
 }
 
 // CreateTable creates google BigQuery table from Table
-func (bq *BigQuery) CreateTable(ctx context.Context, table *Table) error {
+func (bq *BigQuery) CreateTable(ctx context.Context, table *Table) (err error) {
+	defer func() {
+		if err == nil {
+			bq.schemaCache.Invalidate(table.Name)
+		}
+	}()
 	bqTable := bq.client.Dataset(bq.config.Dataset).Table(table.Name)
 
-	_, err := bqTable.Metadata(ctx)
+	_, err = bqTable.Metadata(ctx)
 	if err == nil {
 		logging.Info("BigQuery table", table.Name, "already exists")
 		return nil
@@ -249,6 +572,23 @@ func (bq *BigQuery) CreateTable(ctx context.Context, table *Table) error {
 		}
 		tableMetaData.TimePartitioning = &bigquery.TimePartitioning{Field: table.Partition.Field, Type: partitioningType}
 	}
+	physicalOpts := TablePhysicalOptionsFromContext(ctx)
+	clusteringFields := physicalOpts.ClusteringFields
+	if len(clusteringFields) == 0 {
+		// No explicit clustering configured: default to the primary key, so the MERGE statement
+		// CopyTables runs for PK'd tables can prune on cluster keys instead of a full-table scan.
+		clusteringFields = table.GetPKFields()
+	}
+	if len(clusteringFields) > 0 {
+		tableMetaData.Clustering = &bigquery.Clustering{Fields: clusteringFields}
+	}
+	if tableMetaData.TimePartitioning != nil {
+		tableMetaData.TimePartitioning.Expiration = physicalOpts.PartitionExpiration
+		tableMetaData.TimePartitioning.RequirePartitionFilter = physicalOpts.RequirePartitionFilter
+	}
+	if !physicalOpts.TableExpiration.IsZero() {
+		tableMetaData.ExpirationTime = physicalOpts.TableExpiration
+	}
 	if err := bqTable.Create(ctx, &tableMetaData); err != nil {
 		schemaJson, _ := bqSchema.ToJSONFields()
 		return errorj.GetTableError.Wrap(err, "failed to create table").
@@ -261,9 +601,99 @@ func (bq *BigQuery) CreateTable(ctx context.Context, table *Table) error {
 			})
 	}
 
+	if pkFields := table.GetPKFields(); len(pkFields) > 0 {
+		if err := bq.addPrimaryKeyConstraint(ctx, table.Name, pkFields); err != nil {
+			return errorj.GetTableError.Wrap(err, "failed to add primary key constraint").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset:     bq.config.Dataset,
+					Bucket:      bq.config.Bucket,
+					Project:     bq.config.Project,
+					Table:       table.Name,
+					PrimaryKeys: pkFields,
+				})
+		}
+	}
+
 	return nil
 }
 
+// addPrimaryKeyConstraint declares fields as tableName's native (NOT ENFORCED) primary key via DDL:
+// https://cloud.google.com/bigquery/docs/reference/standard-sql/data-definition-language#add_primary_key.
+// NOT ENFORCED is mandatory for BigQuery PKs; bulker still relies on MERGE/dedup logic, not the
+// database, to actually keep rows unique.
+func (bq *BigQuery) addPrimaryKeyConstraint(ctx context.Context, tableName string, pkFields []string) error {
+	quoted := make([]string, len(pkFields))
+	for i, field := range pkFields {
+		quoted[i] = "`" + field + "`"
+	}
+	queryStr := fmt.Sprintf("ALTER TABLE `%s.%s.%s` ADD PRIMARY KEY (%s) NOT ENFORCED",
+		bq.config.Project, bq.config.Dataset, tableName, strings.Join(quoted, ", "))
+	bq.logQuery("Add primary key constraint: ", queryStr)
+	query := bq.client.Query(queryStr)
+	query.JobID = bqJobID("add_primary_key")
+	return bq.runJobWithRetry(ctx, true, func() (*bigquery.Job, error) {
+		return query.Run(ctx)
+	})
+}
+
+// dropPrimaryKeyConstraint removes tableName's native primary key constraint, if one exists.
+func (bq *BigQuery) dropPrimaryKeyConstraint(ctx context.Context, tableName string) error {
+	queryStr := fmt.Sprintf("ALTER TABLE `%s.%s.%s` DROP PRIMARY KEY IF EXISTS",
+		bq.config.Project, bq.config.Dataset, tableName)
+	bq.logQuery("Drop primary key constraint: ", queryStr)
+	query := bq.client.Query(queryStr)
+	query.JobID = bqJobID("drop_primary_key")
+	return bq.runJobWithRetry(ctx, true, func() (*bigquery.Job, error) {
+		return query.Run(ctx)
+	})
+}
+
+// bqPrimaryKeyFieldsQuery reads back the native primary key BigQuery table constraints declared by
+// addPrimaryKeyConstraint, via the dataset's INFORMATION_SCHEMA views.
+const bqPrimaryKeyFieldsQuery = "SELECT kcu.column_name FROM `%s.%s.INFORMATION_SCHEMA.TABLE_CONSTRAINTS` tco " +
+	"JOIN `%s.%s.INFORMATION_SCHEMA.KEY_COLUMN_USAGE` kcu ON kcu.constraint_name = tco.constraint_name " +
+	"WHERE tco.constraint_type = 'PRIMARY KEY' AND tco.table_name = @table_name"
+
+// getPrimaryKeyFields returns tableName's native primary key columns, or an empty set if it has none.
+func (bq *BigQuery) getPrimaryKeyFields(ctx context.Context, tableName string) (utils.Set[string], error) {
+	queryStr := fmt.Sprintf(bqPrimaryKeyFieldsQuery, bq.config.Project, bq.config.Dataset, bq.config.Project, bq.config.Dataset)
+	query := bq.client.Query(queryStr)
+	query.Parameters = []bigquery.QueryParameter{{Name: "table_name", Value: tableName}}
+
+	it, err := query.Read(ctx)
+	if err != nil {
+		return nil, errorj.GetPrimaryKeysError.Wrap(err, "failed to get primary key").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Dataset:   bq.config.Dataset,
+				Bucket:    bq.config.Bucket,
+				Project:   bq.config.Project,
+				Table:     tableName,
+				Statement: queryStr,
+			})
+	}
+
+	pkFields := utils.Set[string]{}
+	for {
+		var row []bigquery.Value
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errorj.GetPrimaryKeysError.Wrap(err, "failed to scan result").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset:   bq.config.Dataset,
+					Bucket:    bq.config.Bucket,
+					Project:   bq.config.Project,
+					Table:     tableName,
+					Statement: queryStr,
+				})
+		}
+		pkFields[row[0].(string)] = struct{}{}
+	}
+	return pkFields, nil
+}
+
 // InitDatabase creates google BigQuery Dataset if doesn't exist
 func (bq *BigQuery) InitDatabase(ctx context.Context) error {
 	dataset := bq.config.Dataset
@@ -289,8 +719,49 @@ func (bq *BigQuery) InitDatabase(ctx context.Context) error {
 	return nil
 }
 
-// PatchTableSchema adds Table columns to google BigQuery table
-func (bq *BigQuery) PatchTableSchema(ctx context.Context, patchSchema *Table) error {
+// bigqueryTypeWidening lists column type changes BigQuery can apply in place via
+// `ALTER TABLE ... ALTER COLUMN ... SET DATA TYPE`, per
+// https://cloud.google.com/bigquery/docs/managing-table-schemas#modify_column_type.
+var bigqueryTypeWidening = map[bigquery.FieldType]map[bigquery.FieldType]bool{
+	bigquery.IntegerFieldType:    {bigquery.FloatFieldType: true, bigquery.NumericFieldType: true, bigquery.BigNumericFieldType: true},
+	bigquery.NumericFieldType:    {bigquery.BigNumericFieldType: true, bigquery.FloatFieldType: true},
+	bigquery.BigNumericFieldType: {bigquery.FloatFieldType: true},
+	bigquery.DateFieldType:       {bigquery.DateTimeFieldType: true},
+	bigquery.DateTimeFieldType:   {bigquery.TimestampFieldType: true},
+}
+
+// bigqueryTypeCastable lists value-preserving conversions ALTER COLUMN can't apply in place, but
+// that a shadow-copy migration (CAST into a freshly created table, then swap) can. They're all
+// widenings into STRING, which can always represent the source type's values.
+var bigqueryTypeCastable = map[bigquery.FieldType]map[bigquery.FieldType]bool{
+	bigquery.IntegerFieldType:    {bigquery.StringFieldType: true},
+	bigquery.FloatFieldType:      {bigquery.StringFieldType: true},
+	bigquery.NumericFieldType:    {bigquery.StringFieldType: true},
+	bigquery.BigNumericFieldType: {bigquery.StringFieldType: true},
+	bigquery.BooleanFieldType:    {bigquery.StringFieldType: true},
+	bigquery.DateFieldType:       {bigquery.StringFieldType: true},
+	bigquery.DateTimeFieldType:   {bigquery.StringFieldType: true},
+	bigquery.TimestampFieldType:  {bigquery.StringFieldType: true},
+}
+
+// columnTypeCast is a column whose type is widening via migrateColumnTypesByCopy rather than
+// ALTER COLUMN.
+type columnTypeCast struct {
+	name string
+	to   bigquery.FieldType
+}
+
+// PatchTableSchema adds patchSchema's new columns to the google BigQuery table and, for columns that
+// already exist with a narrower type, widens them: in place via ALTER COLUMN when BigQuery supports
+// that conversion directly, or via migrateColumnTypesByCopy otherwise. A type change that isn't a
+// supported widening (e.g. STRING -> INTEGER) fails fast with a clear error instead of being sent to
+// BigQuery, which would reject it with a far less actionable message.
+func (bq *BigQuery) PatchTableSchema(ctx context.Context, patchSchema *Table) (err error) {
+	defer func() {
+		if err == nil {
+			bq.schemaCache.Invalidate(patchSchema.Name)
+		}
+	}()
 	bqTable := bq.client.Dataset(bq.config.Dataset).Table(patchSchema.Name)
 	metadata, err := bqTable.Metadata(ctx)
 	if err != nil {
@@ -303,12 +774,91 @@ func (bq *BigQuery) PatchTableSchema(ctx context.Context, patchSchema *Table) er
 			})
 	}
 
+	existingByName := make(map[string]*bigquery.FieldSchema, len(metadata.Schema))
+	for _, field := range metadata.Schema {
+		existingByName[field.Name] = field
+	}
+
+	var newColumns []*bigquery.FieldSchema
+	var alteredColumns []*bigquery.FieldSchema
+	var castColumns []columnTypeCast
 	for _, columnName := range patchSchema.SortedColumnNames() {
 		column := patchSchema.Columns[columnName]
 		bigQueryType := bigquery.FieldType(strings.ToUpper(column.GetDDLType()))
-		metadata.Schema = append(metadata.Schema, &bigquery.FieldSchema{Name: columnName, Type: bigQueryType})
+
+		existing, exists := existingByName[columnName]
+		if !exists {
+			newColumns = append(newColumns, &bigquery.FieldSchema{Name: columnName, Type: bigQueryType})
+			continue
+		}
+		if existing.Type == bigQueryType {
+			continue
+		}
+		switch {
+		case bigqueryTypeWidening[existing.Type][bigQueryType]:
+			alteredColumns = append(alteredColumns, &bigquery.FieldSchema{Name: columnName, Type: bigQueryType})
+		case bigqueryTypeCastable[existing.Type][bigQueryType]:
+			castColumns = append(castColumns, columnTypeCast{name: columnName, to: bigQueryType})
+		default:
+			return errorj.PatchTableError.Wrap(
+				fmt.Errorf("column %s: unsupported type change from %s to %s", columnName, existing.Type, bigQueryType),
+				"incompatible column type narrowing").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset: bq.config.Dataset,
+					Bucket:  bq.config.Bucket,
+					Project: bq.config.Project,
+					Table:   patchSchema.Name,
+				})
+		}
 	}
+
+	if len(castColumns) > 0 {
+		if err := bq.migrateColumnTypesByCopy(ctx, patchSchema.Name, castColumns); err != nil {
+			return errorj.PatchTableError.Wrap(err, "failed to migrate column types via shadow copy").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset: bq.config.Dataset,
+					Bucket:  bq.config.Bucket,
+					Project: bq.config.Project,
+					Table:   patchSchema.Name,
+				})
+		}
+		// migrateColumnTypesByCopy swaps in a freshly created table, so metadata (and especially its
+		// ETag) no longer describes the live table; re-fetch before applying anything else to it.
+		metadata, err = bqTable.Metadata(ctx)
+		if err != nil {
+			return errorj.PatchTableError.Wrap(err, "failed to get table metadata after schema migration").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset: bq.config.Dataset,
+					Bucket:  bq.config.Bucket,
+					Project: bq.config.Project,
+					Table:   patchSchema.Name,
+				})
+		}
+	}
+	if len(alteredColumns) > 0 {
+		if err := bq.alterColumnTypes(ctx, patchSchema.Name, alteredColumns); err != nil {
+			return errorj.PatchTableError.Wrap(err, "failed to widen column types").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset: bq.config.Dataset,
+					Bucket:  bq.config.Bucket,
+					Project: bq.config.Project,
+					Table:   patchSchema.Name,
+				})
+		}
+	}
+
+	metadata.Schema = append(metadata.Schema, newColumns...)
 	updateReq := bigquery.TableMetadataToUpdate{Schema: metadata.Schema}
+
+	physicalOpts := TablePhysicalOptionsFromContext(ctx)
+	clusteringFields := physicalOpts.ClusteringFields
+	if len(clusteringFields) == 0 {
+		clusteringFields = patchSchema.GetPKFields()
+	}
+	if len(clusteringFields) > 0 {
+		updateReq.Clustering = &bigquery.Clustering{Fields: clusteringFields}
+	}
+
 	bq.logQuery("PATCH update request: ", updateReq)
 	if _, err := bqTable.Update(ctx, updateReq, metadata.ETag); err != nil {
 		schemaJson, _ := metadata.Schema.ToJSONFields()
@@ -322,9 +872,115 @@ func (bq *BigQuery) PatchTableSchema(ctx context.Context, patchSchema *Table) er
 			})
 	}
 
+	return bq.reconcilePrimaryKey(ctx, patchSchema.Name, patchSchema.GetPKFields())
+}
+
+// reconcilePrimaryKey makes tableName's native primary key constraint match pkFields, dropping and
+// re-adding it only when it actually differs (BigQuery DDL has no "ALTER COLUMN"-style in-place
+// modify for constraints).
+func (bq *BigQuery) reconcilePrimaryKey(ctx context.Context, tableName string, pkFields []string) error {
+	existing, err := bq.getPrimaryKeyFields(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	if len(existing) == len(pkFields) {
+		same := true
+		for _, field := range pkFields {
+			if _, ok := existing[field]; !ok {
+				same = false
+				break
+			}
+		}
+		if same {
+			return nil
+		}
+	}
+	if len(existing) > 0 {
+		if err := bq.dropPrimaryKeyConstraint(ctx, tableName); err != nil {
+			return errorj.PatchTableError.Wrap(err, "failed to drop primary key constraint").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset: bq.config.Dataset,
+					Bucket:  bq.config.Bucket,
+					Project: bq.config.Project,
+					Table:   tableName,
+				})
+		}
+	}
+	if len(pkFields) == 0 {
+		return nil
+	}
+	if err := bq.addPrimaryKeyConstraint(ctx, tableName, pkFields); err != nil {
+		return errorj.PatchTableError.Wrap(err, "failed to add primary key constraint").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Dataset:     bq.config.Dataset,
+				Bucket:      bq.config.Bucket,
+				Project:     bq.config.Project,
+				Table:       tableName,
+				PrimaryKeys: pkFields,
+			})
+	}
+	return nil
+}
+
+// alterColumnTypes widens columns in place via `ALTER TABLE ... ALTER COLUMN ... SET DATA TYPE`, one
+// statement per column so a single unsupported conversion doesn't abort the others.
+func (bq *BigQuery) alterColumnTypes(ctx context.Context, tableName string, columns []*bigquery.FieldSchema) error {
+	for _, column := range columns {
+		queryStr := fmt.Sprintf("ALTER TABLE `%s.%s.%s` ALTER COLUMN `%s` SET DATA TYPE %s",
+			bq.config.Project, bq.config.Dataset, tableName, column.Name, column.Type)
+		bq.logQuery("Widen column type: ", queryStr)
+		query := bq.client.Query(queryStr)
+		query.JobID = bqJobID("alter_column_type")
+		if err := bq.runJobWithRetry(ctx, true, func() (*bigquery.Job, error) {
+			return query.Run(ctx)
+		}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// migrateColumnTypesByCopy widens casts's columns to a type ALTER COLUMN can't convert in place
+// (but that's still value-preserving, e.g. INT64 -> STRING) by creating a copy of tableName with
+// those columns CAST to their new type, then swapping it in via ReplaceTable.
+func (bq *BigQuery) migrateColumnTypesByCopy(ctx context.Context, tableName string, casts []columnTypeCast) error {
+	meta, err := bq.client.Dataset(bq.config.Dataset).Table(tableName).Metadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	castTo := make(map[string]bigquery.FieldType, len(casts))
+	for _, c := range casts {
+		castTo[c.name] = c.to
+	}
+	selectCols := make([]string, len(meta.Schema))
+	for i, field := range meta.Schema {
+		if to, ok := castTo[field.Name]; ok {
+			selectCols[i] = fmt.Sprintf("CAST(`%s` AS %s) AS `%s`", field.Name, to, field.Name)
+		} else {
+			selectCols[i] = "`" + field.Name + "`"
+		}
+	}
+
+	shadowName := tableName + "_schema_migration_" + utils.SanitizeString(bqJobID(""))
+	queryStr := fmt.Sprintf("CREATE OR REPLACE TABLE `%s.%s.%s` AS SELECT %s FROM `%s.%s.%s`",
+		bq.config.Project, bq.config.Dataset, shadowName,
+		strings.Join(selectCols, ", "),
+		bq.config.Project, bq.config.Dataset, tableName,
+	)
+	bq.logQuery("Schema migration shadow copy: ", queryStr)
+
+	query := bq.client.Query(queryStr)
+	query.JobID = bqJobID("schema_migration_copy")
+	if err := bq.runJobWithRetry(ctx, true, func() (*bigquery.Job, error) {
+		return query.Run(ctx)
+	}); err != nil {
+		return err
+	}
+
+	return bq.ReplaceTable(ctx, tableName, shadowName, true)
+}
+
 func (bq *BigQuery) DeletePartition(ctx context.Context, tableName string, datePartiton *DatePartition) error {
 	partitions := GranularityToPartitionIds(datePartiton.Granularity, datePartiton.Value)
 	for _, partition := range partitions {
@@ -379,9 +1035,52 @@ func GranularityToPartitionIds(g Granularity, t time.Time) []string {
 	}
 }
 
-// insertBatch streams data into BQ using stream API
-// 1 insert = max 500 rows
-func (bq *BigQuery) Insert(ctx context.Context, table *Table, merge bool, objects []types.Object) error {
+// Insert loads objects into table. By default it stages them as a gzip'd newline-delimited JSON batch
+// load job, which has no per-request row cap and (unlike the streaming API below) doesn't leave rows
+// invisible to DML for up to 90 minutes. An AutoCommit stream opened with WithStreamingInsert instead
+// uses BigQuery's legacy streaming Inserter.Put API, 500 rows per request.
+func (bq *BigQuery) Insert(ctx context.Context, table *Table, merge bool, objects []types.Object) (err error) {
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeInsert != nil {
+		if objects, err = hooks.BeforeInsert(ctx, table, objects); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		runOnError(ctx, hooks, "insert", err)
+		if err == nil && hooks.AfterInsert != nil {
+			hooks.AfterInsert(ctx, table, InsertStats{Objects: len(objects)})
+		}
+	}()
+
+	if StorageWriteAPIFromContext(ctx) {
+		bq.logQuery(fmt.Sprintf("Appending [%d] values to table %s using the BigQuery Storage Write API: ", len(objects), table.Name), objects)
+		if err = bq.insertViaStorageWrite(ctx, table, objects); err != nil {
+			return errorj.ExecuteInsertInBatchError.Wrap(err, "failed to append batch via Storage Write API").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset: bq.config.Dataset,
+					Bucket:  bq.config.Bucket,
+					Project: bq.config.Project,
+					Table:   table.Name,
+				})
+		}
+		return nil
+	}
+
+	if ModeFromContext(ctx) != bulker.AutoCommit || !StreamingInsertFromContext(ctx) {
+		bq.logQuery(fmt.Sprintf("Loading [%d] values into table %s using a BigQuery batch load job: ", len(objects), table.Name), objects)
+		if err = bq.insertViaLoadJob(ctx, table, objects); err != nil {
+			return errorj.ExecuteInsertInBatchError.Wrap(err, "failed to load batch into table").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset: bq.config.Dataset,
+					Bucket:  bq.config.Bucket,
+					Project: bq.config.Project,
+					Table:   table.Name,
+				})
+		}
+		return nil
+	}
+
 	inserter := bq.client.Dataset(bq.config.Dataset).Table(table.Name).Inserter()
 	bq.logQuery(fmt.Sprintf("Inserting [%d] values to table %s using BigQuery Streaming API with chunks [%d]: ", len(objects), table.Name, rowsLimitPerInsertOperation), objects)
 
@@ -455,16 +1154,10 @@ func (bq *BigQuery) ReplaceTable(ctx context.Context, originalTable, replacement
 	dataset := bq.client.Dataset(bq.config.Dataset)
 	copier := dataset.Table(originalTable).CopierFrom(dataset.Table(replacementTable))
 	copier.WriteDisposition = bigquery.WriteTruncate
-	job, err := copier.Run(ctx)
-	if err != nil {
-		return err
-	}
-	status, err := job.Wait(ctx)
-	if err != nil {
-		return err
-
-	}
-	if err = status.Err(); err != nil {
+	copier.JobID = bqJobID("replace_table")
+	if err = bq.runJobWithRetry(ctx, true, func() (*bigquery.Job, error) {
+		return copier.Run(ctx)
+	}); err != nil {
 		return err
 	}
 	if dropOldTable {
@@ -476,9 +1169,14 @@ func (bq *BigQuery) ReplaceTable(ctx context.Context, originalTable, replacement
 
 // TruncateTable deletes all records in tableName table
 func (bq *BigQuery) TruncateTable(ctx context.Context, tableName string) error {
-	query := fmt.Sprintf(truncateBigQueryTemplate, bq.config.Project, bq.config.Dataset, tableName)
-	bq.logQuery(query, nil)
-	if _, err := bq.client.Query(query).Read(ctx); err != nil {
+	queryStr := fmt.Sprintf(truncateBigQueryTemplate, bq.config.Project, bq.config.Dataset, tableName)
+	bq.logQuery(queryStr, nil)
+	query := bq.client.Query(queryStr)
+	query.JobID = bqJobID("truncate_table")
+	if err := withRetry(ctx, true, func(attempt int) error {
+		_, err := query.Read(ctx)
+		return err
+	}); err != nil {
 		extraText := ""
 		if strings.Contains(err.Error(), "Not found") {
 			extraText = ": " + ErrTableNotExist.Error()
@@ -513,6 +1211,475 @@ func (bq *BigQuery) insertItems(ctx context.Context, inserter *bigquery.Inserter
 	return nil
 }
 
+// getStorageWriteClient returns bq's shared managedwriter.Client, creating it on first use.
+func (bq *BigQuery) getStorageWriteClient(ctx context.Context) (*managedwriter.Client, error) {
+	bq.storageWriteClientMu.Lock()
+	defer bq.storageWriteClientMu.Unlock()
+	if bq.storageWriteClient != nil {
+		return bq.storageWriteClient, nil
+	}
+	var client *managedwriter.Client
+	var err error
+	if bq.config.Credentials == nil {
+		client, err = managedwriter.NewClient(ctx, bq.config.Project)
+	} else {
+		client, err = managedwriter.NewClient(ctx, bq.config.Project, bq.config.Credentials)
+	}
+	if err != nil {
+		return nil, err
+	}
+	bq.storageWriteClient = client
+	return client, nil
+}
+
+// insertViaStorageWrite appends objects to table via the Storage Write API's default stream
+// (at-least-once semantics, visible to readers as soon as each AppendRows call returns). It covers the
+// row-at-a-time streaming use case; committing a batch atomically through a pending stream is left as a
+// follow-up for callers that need exactly-once commit rather than at-least-once append.
+func (bq *BigQuery) insertViaStorageWrite(ctx context.Context, table *Table, objects []types.Object) error {
+	client, err := bq.getStorageWriteClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create Storage Write API client: %w", err)
+	}
+
+	bqSchema := bigquery.Schema{}
+	for _, columnName := range table.SortedColumnNames() {
+		column := table.Columns[columnName]
+		bqSchema = append(bqSchema, &bigquery.FieldSchema{Name: columnName, Type: bigquery.FieldType(strings.ToUpper(column.GetDDLType()))})
+	}
+	storageSchema, err := adapt.BQSchemaToStorageTableSchema(bqSchema)
+	if err != nil {
+		return fmt.Errorf("failed to convert table schema for Storage Write API: %w", err)
+	}
+	rawDescriptor, err := adapt.StorageSchemaToProto2Descriptor(storageSchema, "root")
+	if err != nil {
+		return fmt.Errorf("failed to build proto descriptor for Storage Write API: %w", err)
+	}
+	messageDescriptor, ok := rawDescriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return fmt.Errorf("unexpected descriptor type %T from Storage Write API schema conversion", rawDescriptor)
+	}
+	normalizedDescriptor, err := adapt.NormalizeDescriptor(messageDescriptor)
+	if err != nil {
+		return fmt.Errorf("failed to normalize proto descriptor for Storage Write API: %w", err)
+	}
+
+	stream, err := client.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(
+			managedwriter.TableParentFromParts(bq.config.Project, bq.config.Dataset, table.Name)),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(normalizedDescriptor),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to open Storage Write API stream: %w", err)
+	}
+	defer stream.Close()
+
+	rows := make([][]byte, 0, len(objects))
+	for _, object := range objects {
+		row, err := bqObjectToProtoRow(messageDescriptor, object)
+		if err != nil {
+			return fmt.Errorf("failed to encode row for Storage Write API: %w", err)
+		}
+		rows = append(rows, row)
+	}
+
+	result, err := stream.AppendRows(ctx, rows)
+	if err != nil {
+		return err
+	}
+	_, err = result.GetResult(ctx)
+	return err
+}
+
+// bqObjectToProtoRow encodes object as a wire-format message matching md, by round-tripping it through
+// JSON into a dynamicpb message: the descriptor's field names already match object's keys (both come
+// from the same Table.Columns), so protojson's field-name matching does the mapping for free without
+// hand-writing a types.DataType-to-protoreflect.Kind conversion for every scalar.
+func bqObjectToProtoRow(md protoreflect.MessageDescriptor, object types.Object) ([]byte, error) {
+	jsonBytes, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal(jsonBytes, msg); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+// LoadTable loads loadSource's newline-delimited JSON rows into targetTable via a BigQuery batch load
+// job. When config.Bucket is set, the file is uploaded to GCS first and loaded from there, enabling
+// BigQuery's parallel slot-based ingestion instead of streaming the file through this host's network
+// link; the staged object is deleted once the load job finishes. With no bucket configured, the file
+// is loaded directly as a ReaderSource, same as before GCS staging was an option.
+func (bq *BigQuery) LoadTable(ctx context.Context, targetTable *Table, loadSource *LoadSource) (err error) {
+	if loadSource.Type != LocalFile {
+		return fmt.Errorf("LoadTable: only local file is supported")
+	}
+	if loadSource.Format != JSON {
+		return fmt.Errorf("LoadTable: only JSON format is supported")
+	}
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeLoadTable != nil {
+		if err = hooks.BeforeLoadTable(ctx, targetTable, loadSource); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		runOnError(ctx, hooks, "loadTable", err)
+		if err != nil {
+			err = errorj.LoadError.Wrap(err, "failed to load table").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Dataset:     bq.config.Dataset,
+					Bucket:      bq.config.Bucket,
+					Project:     bq.config.Project,
+					Table:       targetTable.Name,
+					PrimaryKeys: targetTable.GetPKFields(),
+				})
+		} else if hooks.AfterLoadTable != nil {
+			hooks.AfterLoadTable(ctx, targetTable)
+		}
+	}()
+
+	disposition := bigquery.WriteAppend
+	if ModeFromContext(ctx) == bulker.ReplaceTable {
+		disposition = bigquery.WriteTruncate
+	}
+	// A load landing in a table that's swapped in afterwards (ReplaceTable) can't leave duplicate rows
+	// behind if retried; other modes only retry on errors classified as transient by isRetryable.
+	idempotent := disposition == bigquery.WriteTruncate
+	bqTable := bq.client.Dataset(bq.config.Dataset).Table(targetTable.Name)
+
+	if bq.config.Bucket == "" {
+		file, ferr := os.Open(loadSource.Path)
+		if ferr != nil {
+			return ferr
+		}
+		defer file.Close()
+
+		readerSource := bigquery.NewReaderSource(file)
+		readerSource.SourceFormat = bigquery.JSON
+		loader := bqTable.LoaderFrom(readerSource)
+		loader.CreateDisposition = bigquery.CreateNever
+		loader.WriteDisposition = disposition
+		loader.JobID = bqJobID("load_table_local")
+		return bq.runLoadJobWithRetry(ctx, idempotent, loader)
+	}
+
+	objectName := fmt.Sprintf("%s/%s/%s", bigQueryLoadObjectDir, targetTable.Name, filepath.Base(loadSource.Path))
+	if err := bq.uploadFileToGCS(ctx, loadSource.Path, objectName); err != nil {
+		return fmt.Errorf("failed to stage load file to GCS: %w", err)
+	}
+	defer func() {
+		_ = bq.storageClient.Bucket(bq.config.Bucket).Object(objectName).Delete(context.Background())
+	}()
+
+	gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", bq.config.Bucket, objectName))
+	gcsRef.SourceFormat = bigquery.JSON
+	loader := bqTable.LoaderFrom(gcsRef)
+	loader.CreateDisposition = bigquery.CreateNever
+	loader.WriteDisposition = disposition
+	loader.JobID = bqJobID("load_table_gcs")
+	return bq.runLoadJobWithRetry(ctx, idempotent, loader)
+}
+
+// uploadFileToGCS copies localPath's contents to objectName in config.Bucket.
+func (bq *BigQuery) uploadFileToGCS(ctx context.Context, localPath, objectName string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bq.storageClient.Bucket(bq.config.Bucket).Object(objectName).NewWriter(ctx)
+	if _, err := io.Copy(writer, file); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// runLoadJobWithRetry runs loader and waits for it to finish, retrying transient failures with
+// withRetry's backoff and surfacing any per-row job errors the same way insertViaLoadJob does.
+func (bq *BigQuery) runLoadJobWithRetry(ctx context.Context, idempotent bool, loader *bigquery.Loader) error {
+	return withRetry(ctx, idempotent, func(attempt int) error {
+		job, err := loader.Run(ctx)
+		if err != nil {
+			return err
+		}
+		jobStatus, err := job.Wait(ctx)
+		if err != nil {
+			return err
+		}
+		if len(jobStatus.Errors) > 0 {
+			return wrapBQJobErrors(jobStatus.Errors)
+		}
+		return jobStatus.Err()
+	})
+}
+
+// insertViaLoadJob stages objects as one or more gzip'd newline-delimited JSON objects in GCS (see
+// stageNDJSON) and loads each with its own batch load job, using WriteTruncate for ReplaceTable streams
+// (which land in a table that's swapped in afterwards, so a retried load can't duplicate rows) and
+// WriteAppend otherwise.
+func (bq *BigQuery) insertViaLoadJob(ctx context.Context, table *Table, objects []types.Object) error {
+	disposition := bigquery.WriteAppend
+	if ModeFromContext(ctx) == bulker.ReplaceTable {
+		disposition = bigquery.WriteTruncate
+	}
+
+	pool, err := bq.storagePool(ctx, StoragePoolSizeFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to build GCS storage pool: %w", err)
+	}
+	if pool != nil {
+		return bq.insertViaLoadJobPooled(ctx, table, objects, pool, disposition)
+	}
+
+	objectNames, err := bq.stageNDJSON(ctx, table.Name, objects)
+	if err != nil {
+		return fmt.Errorf("failed to stage objects in GCS: %w", err)
+	}
+	defer func() {
+		for _, objectName := range objectNames {
+			_ = bq.storageClient.Bucket(bq.config.Bucket).Object(objectName).Delete(context.Background())
+		}
+	}()
+
+	bqTable := bq.client.Dataset(bq.config.Dataset).Table(table.Name)
+	for i, objectName := range objectNames {
+		gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", bq.config.Bucket, objectName))
+		gcsRef.SourceFormat = bigquery.JSON
+		loader := bqTable.LoaderFrom(gcsRef)
+		loader.CreateDisposition = bigquery.CreateNever
+		loader.WriteDisposition = disposition
+		loader.JobID = bqJobID(fmt.Sprintf("load_chunk_%d", i))
+
+		if err := withRetry(ctx, true, func(attempt int) error {
+			job, err := loader.Run(ctx)
+			if err != nil {
+				return err
+			}
+			jobStatus, err := job.Wait(ctx)
+			if err != nil {
+				return err
+			}
+			if len(jobStatus.Errors) > 0 {
+				return wrapBQJobErrors(jobStatus.Errors)
+			}
+			return jobStatus.Err()
+		}); err != nil {
+			return fmt.Errorf("failed to run load job for chunk %d of %d: %w", i+1, len(objectNames), err)
+		}
+	}
+	return nil
+}
+
+// stageNDJSON marshals objects to GCS as gzip'd newline-delimited JSON, splitting across as many
+// objects as needed to keep each one under bigQueryLoadChunkBytes, and returns their object names.
+func (bq *BigQuery) stageNDJSON(ctx context.Context, tableName string, objects []types.Object) ([]string, error) {
+	var objectNames []string
+	var writer *storage.Writer
+	var gzWriter *gzip.Writer
+	chunkBytes := 0
+	chunkIndex := 0
+
+	closeChunk := func() error {
+		if gzWriter == nil {
+			return nil
+		}
+		if err := gzWriter.Close(); err != nil {
+			return err
+		}
+		err := writer.Close()
+		gzWriter, writer = nil, nil
+		return err
+	}
+	openChunk := func() {
+		objectName := fmt.Sprintf("%s/%s_%d.ndjson.gz", bigQueryStageObjectDir, tableName, chunkIndex)
+		chunkIndex++
+		objectNames = append(objectNames, objectName)
+		writer = bq.storageClient.Bucket(bq.config.Bucket).Object(objectName).NewWriter(ctx)
+		gzWriter = gzip.NewWriter(writer)
+		chunkBytes = 0
+	}
+
+	for _, object := range objects {
+		line, err := json.Marshal(object)
+		if err != nil {
+			_ = closeChunk()
+			return nil, err
+		}
+		line = append(line, '\n')
+		if gzWriter == nil {
+			openChunk()
+		}
+		if _, err := gzWriter.Write(line); err != nil {
+			_ = closeChunk()
+			return nil, err
+		}
+		chunkBytes += len(line)
+		if chunkBytes >= bigQueryLoadChunkBytes {
+			if err := closeChunk(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := closeChunk(); err != nil {
+		return nil, err
+	}
+	return objectNames, nil
+}
+
+// wrapBQJobErrors renders a load job's per-row errors as the same multierror shape insertItems already
+// produces from a streaming PutMultiError, so Insert's callers don't need to branch on which path ran.
+func wrapBQJobErrors(errs []*bigquery.Error) error {
+	var multiErr error
+	for _, e := range errs {
+		multiErr = multierror.Append(multiErr, errors.New(e.Error()))
+	}
+	return multiErr
+}
+
+// JobStats summarizes a DryRun job: the bytes BigQuery estimates it would process, the tables it would
+// read, and (where the job type produces one) the resulting schema, all without the job actually running.
+type JobStats struct {
+	TotalBytesProcessed int64
+	ReferencedTables    []string
+	Schema              *Table
+}
+
+// DryRunOperation selects which real operation DryRun estimates.
+type DryRunOperation string
+
+const (
+	DryRunUpdate     DryRunOperation = "update"
+	DryRunDelete     DryRunOperation = "delete"
+	DryRunTruncate   DryRunOperation = "truncate"
+	DryRunCopyTables DryRunOperation = "copyTables"
+	DryRunLoad       DryRunOperation = "load"
+	DryRunSelect     DryRunOperation = "select"
+)
+
+// DryRunRequest carries whichever of Update/Delete/TruncateTable/CopyTables/Copy/Select's arguments
+// Operation needs; fields unused by the chosen Operation are ignored.
+type DryRunRequest struct {
+	Operation      DryRunOperation
+	TableName      string
+	Object         types.Object
+	WhenConditions *WhenConditions
+	TargetTable    *Table
+	SourceTable    *Table
+	FileKey        string
+	CopyOptions    CopyOptions
+}
+
+// DryRun estimates the cost of Update, Delete, TruncateTable, Select, a primary-key MERGE CopyTables (the
+// same statement MergeInto runs), or the Copy/Insert load path without running it, via
+// query.DryRun/loader.DryRun, and reads the estimate back off the job's statistics. Use this to gate an
+// expensive merge/replace behind a byte-budget check before letting the real job run against a multi-TB
+// partitioned table.
+func (bq *BigQuery) DryRun(ctx context.Context, req DryRunRequest) (*JobStats, error) {
+	switch req.Operation {
+	case DryRunUpdate:
+		updateCondition, updateValues, err := bq.toWhenConditions(ctx, req.WhenConditions)
+		if err != nil {
+			return nil, err
+		}
+		columns := make([]string, 0, len(req.Object))
+		values := make([]bigquery.QueryParameter, 0, len(req.Object)+len(updateValues))
+		for name, value := range req.Object {
+			columns = append(columns, name+"= @"+name)
+			values = append(values, bigquery.QueryParameter{Name: name, Value: value})
+		}
+		values = append(values, updateValues...)
+		query := fmt.Sprintf(updateBigQueryTemplate, bq.config.Project, bq.config.Dataset, req.TableName, strings.Join(columns, ", "), updateCondition)
+		return bq.dryRunQuery(ctx, query, values)
+	case DryRunDelete:
+		whenCondition, values, err := bq.toWhenConditions(ctx, req.WhenConditions)
+		if err != nil {
+			return nil, err
+		}
+		query := fmt.Sprintf(deleteBigQueryTemplate, bq.config.Project, bq.config.Dataset, req.TableName, whenCondition)
+		return bq.dryRunQuery(ctx, query, values)
+	case DryRunTruncate:
+		query := fmt.Sprintf(truncateBigQueryTemplate, bq.config.Project, bq.config.Dataset, req.TableName)
+		return bq.dryRunQuery(ctx, query, nil)
+	case DryRunCopyTables:
+		pkFields := req.TargetTable.GetPKFields()
+		if len(pkFields) == 0 {
+			// a plain TableCopy job (the CopyTables path used when there's no PK to MERGE on) has no
+			// dry-run support in the BigQuery API itself, not just this SDK.
+			return nil, fmt.Errorf("dry run is only supported for CopyTables when the target table has a primary key")
+		}
+		// MergeInto issues this exact MERGE statement against its tmp table, so DryRunCopyTables also
+		// estimates a MergeInto call's cost; there's no separate DryRunMerge operation.
+		query := bq.mergeCopyTablesQuery(req.TargetTable, req.SourceTable, pkFields)
+		return bq.dryRunQuery(ctx, query, nil)
+	case DryRunSelect:
+		whenCondition, values, err := bq.toWhenConditions(ctx, req.WhenConditions)
+		if err != nil {
+			return nil, err
+		}
+		if whenCondition != "" {
+			whenCondition = " WHERE " + whenCondition
+		}
+		query := fmt.Sprintf(selectBigQueryTemplate, "*", bq.config.Project, bq.config.Dataset, req.TableName, whenCondition)
+		return bq.dryRunQuery(ctx, query, values)
+	case DryRunLoad:
+		gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", bq.config.Bucket, req.FileKey))
+		gcsRef.SourceFormat = bigquery.DataFormat(req.CopyOptions.SourceFormat)
+		loader := bq.client.Dataset(bq.config.Dataset).Table(req.TableName).LoaderFrom(gcsRef)
+		loader.DryRun = true
+		job, err := loader.Run(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return jobStats(job)
+	}
+	return nil, fmt.Errorf("unsupported dry run operation: %s", req.Operation)
+}
+
+func (bq *BigQuery) dryRunQuery(ctx context.Context, query string, params []bigquery.QueryParameter) (*JobStats, error) {
+	q := bq.client.Query(query)
+	q.Parameters = params
+	q.DryRun = true
+	job, err := q.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return jobStats(job)
+}
+
+// jobStats reads TotalBytesProcessed, ReferencedTables and the result Schema off a dry-run job's
+// statistics. Only query jobs populate all three; other job types leave the zero value for what they
+// don't report.
+func jobStats(job *bigquery.Job) (*JobStats, error) {
+	status := job.LastStatus()
+	if status == nil || status.Statistics == nil {
+		return &JobStats{}, nil
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+	stats := &JobStats{}
+	if queryStats, ok := status.Statistics.Details.(*bigquery.QueryStatistics); ok {
+		stats.TotalBytesProcessed = queryStats.TotalBytesProcessed
+		for _, t := range queryStats.ReferencedTables {
+			stats.ReferencedTables = append(stats.ReferencedTables, t.TableID)
+		}
+		if queryStats.Schema != nil {
+			schemaTable := &Table{Columns: Columns{}}
+			for _, field := range queryStats.Schema {
+				schemaTable.Columns[field.Name] = SQLColumn{Type: string(field.Type)}
+			}
+			stats.Schema = schemaTable
+		}
+	}
+	return stats, nil
+}
+
 func (bq *BigQuery) toDeleteQuery(conditions *WhenConditions) string {
 	var queryConditions []string
 
@@ -534,6 +1701,10 @@ func (bq *BigQuery) logQuery(messageTemplate string, entity interface{}) {
 }
 
 func (bq *BigQuery) Close() error {
+	if bq.storageWriteClient != nil {
+		_ = bq.storageWriteClient.Close()
+	}
+	_ = bq.storageClient.Close()
 	return bq.client.Close()
 }
 
@@ -559,7 +1730,10 @@ func (bqi *BQItem) Save() (row map[string]bigquery.Value, insertID string, err e
 }
 
 func (bq *BigQuery) Update(ctx context.Context, tableName string, object types.Object, whenConditions *WhenConditions) (err error) {
-	updateCondition, updateValues := bq.toWhenConditions(whenConditions)
+	updateCondition, updateValues, err := bq.toWhenConditions(ctx, whenConditions)
+	if err != nil {
+		return err
+	}
 
 	columns := make([]string, len(object), len(object))
 	values := make([]bigquery.QueryParameter, len(object)+len(updateValues), len(object)+len(updateValues))
@@ -589,34 +1763,73 @@ func (bq *BigQuery) Update(ctx context.Context, tableName string, object types.O
 		}
 	}()
 
-	query := bq.client.Query(updateQuery)
-	query.Parameters = values
-	job, err := query.Run(ctx)
-	if err != nil {
+	_, err = bq.runQueryJobWithRetry(ctx, "update", updateQuery, values)
+	return err
+}
+
+// RowIterator streams Select results one row at a time instead of buffering the whole result set in
+// memory, mirroring the idiomatic iterator.Done pattern google-cloud-go itself uses for
+// *bigquery.RowIterator. Next returns io.EOF once results are exhausted, so callers outside this
+// package don't need to import google-cloud-go just to recognize end-of-stream.
+type RowIterator interface {
+	Next(row *map[string]any) error
+	Close() error
+}
+
+// bqRowIterator adapts a *bigquery.RowIterator to RowIterator, applying the same bigquery.Value ->
+// any coercion selectFrom used to apply eagerly to every buffered row, now done lazily per Next call.
+type bqRowIterator struct {
+	it *bigquery.RowIterator
+}
+
+func (r *bqRowIterator) Next(row *map[string]any) error {
+	var bqRow = map[string]bigquery.Value{}
+	if err := r.it.Next(&bqRow); err != nil {
+		if err == iterator.Done {
+			return io.EOF
+		}
 		return err
 	}
-	status, err := job.Wait(ctx)
-	if err != nil {
-		return err
+	resRow := make(map[string]any, len(bqRow))
+	for k, v := range bqRow {
+		resRow[k] = v
 	}
-	return status.Err()
+	*row = resRow
+	return nil
+}
+
+// Close is a no-op: *bigquery.RowIterator holds no closeable resource of its own, it just lazily pages
+// through the underlying job's results. Still present so callers can always `defer it.Close()`.
+func (r *bqRowIterator) Close() error {
+	return nil
 }
 
 func (bq *BigQuery) Select(ctx context.Context, tableName string, whenConditions *WhenConditions) ([]map[string]any, error) {
 	return bq.selectFrom(ctx, tableName, "*", whenConditions)
 }
-func (bq *BigQuery) selectFrom(ctx context.Context, tableName string, selectExpression string, deleteConditions *WhenConditions) (res []map[string]any, err error) {
-	whenCondition, values := bq.toWhenConditions(deleteConditions)
+
+// SelectStream runs the same query Select does but returns a RowIterator instead of buffering every row
+// into a slice, so large tables can be scanned without OOMing the caller. This is what makes BigQuery a
+// viable source for bulker's replication/CDC read paths.
+func (bq *BigQuery) SelectStream(ctx context.Context, tableName string, whenConditions *WhenConditions) (RowIterator, error) {
+	return bq.selectStreamFrom(ctx, tableName, "*", whenConditions)
+}
+
+func (bq *BigQuery) selectStreamFrom(ctx context.Context, tableName string, selectExpression string, deleteConditions *WhenConditions) (res RowIterator, err error) {
+	whenCondition, values, err := bq.toWhenConditions(ctx, deleteConditions)
+	if err != nil {
+		return nil, err
+	}
 	if whenCondition != "" {
 		whenCondition = " WHERE " + whenCondition
 	}
 	selectQuery := fmt.Sprintf(selectBigQueryTemplate, selectExpression, bq.config.Project, bq.config.Dataset, tableName, whenCondition)
 	defer func() {
-		v := make([]any, len(values))
-		for i, value := range values {
-			v[i] = value.Value
-		}
 		if err != nil {
+			v := make([]any, len(values))
+			for i, value := range values {
+				v[i] = value.Value
+			}
 			err = errorj.SelectFromTableError.Wrap(err, "failed execute select").
 				WithProperty(errorj.DBInfo, &types.ErrorPayload{
 					Dataset:   bq.config.Dataset,
@@ -627,38 +1840,37 @@ func (bq *BigQuery) selectFrom(ctx context.Context, tableName string, selectExpr
 		}
 	}()
 
-	query := bq.client.Query(selectQuery)
-	query.Parameters = values
-	job, err := query.Run(ctx)
+	job, err := bq.runQueryJobWithRetry(ctx, "select", selectQuery, values)
 	if err != nil {
 		return nil, err
 	}
-	status, err := job.Wait(ctx)
+	it, err := job.Read(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if err := status.Err(); err != nil {
-		return nil, err
-	}
-	it, err := job.Read(ctx)
+	return &bqRowIterator{it: it}, nil
+}
+
+// selectFrom is a thin buffering wrapper around selectStreamFrom, kept for callers (Select/Count) that
+// want the whole result set at once.
+func (bq *BigQuery) selectFrom(ctx context.Context, tableName string, selectExpression string, deleteConditions *WhenConditions) ([]map[string]any, error) {
+	it, err := bq.selectStreamFrom(ctx, tableName, selectExpression, deleteConditions)
 	if err != nil {
 		return nil, err
 	}
+	defer it.Close()
+
 	var result []map[string]any
 	for {
-		var row = map[string]bigquery.Value{}
+		var row map[string]any
 		err := it.Next(&row)
-		if err == iterator.Done {
+		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			return nil, err
 		}
-		var resRow = map[string]any{}
-		for k, v := range row {
-			resRow[k] = v
-		}
-		result = append(result, resRow)
+		result = append(result, row)
 	}
 	return result, nil
 }
@@ -674,23 +1886,58 @@ func (bq *BigQuery) Count(ctx context.Context, tableName string, whenConditions
 	return strconv.Atoi(fmt.Sprint(res[0]["jitsu_count"]))
 }
 
-func (bq *BigQuery) toWhenConditions(conditions *WhenConditions) (string, []bigquery.QueryParameter) {
-	if conditions == nil {
-		return "", []bigquery.QueryParameter{}
+// partitionPredicate builds a literal (non-parameterized) partition-range predicate from whichever
+// PartitionKey/PartitionRangeStart/PartitionRangeEnd physical options are attached to ctx. It must be a
+// literal, not a bound @when_ parameter: BigQuery only prunes partitions it can evaluate at plan time,
+// and a query parameter's value isn't known until execution.
+func (bq *BigQuery) partitionPredicate(ctx context.Context) string {
+	opts := TablePhysicalOptionsFromContext(ctx)
+	if opts.PartitionKey == "" {
+		return ""
+	}
+	return fmt.Sprintf("`%s` BETWEEN TIMESTAMP('%s') AND TIMESTAMP('%s')",
+		opts.PartitionKey,
+		opts.PartitionRangeStart.UTC().Format(time.RFC3339Nano),
+		opts.PartitionRangeEnd.UTC().Format(time.RFC3339Nano),
+	)
+}
+
+// toWhenConditions renders conditions as a parameterized WHERE fragment, prefixed with a literal
+// partition-range predicate (see partitionPredicate) when ctx carries one. It returns an error when ctx's
+// TablePhysicalOptions require a partition filter (WithRequirePartitionFilter) but none was configured via
+// WithPartitionRange, since running such a query unfiltered against a require_partition_filter=true
+// dataset would otherwise fail late, with a far less actionable BigQuery error.
+func (bq *BigQuery) toWhenConditions(ctx context.Context, conditions *WhenConditions) (string, []bigquery.QueryParameter, error) {
+	opts := TablePhysicalOptionsFromContext(ctx)
+	if opts.RequirePartitionFilter && opts.PartitionKey == "" {
+		return "", nil, fmt.Errorf("toWhenConditions: require_partition_filter is set but no PartitionKey/PartitionRange was configured (see WithPartitionRange)")
 	}
+
 	var queryConditions []string
-	var values []bigquery.QueryParameter
+	if partition := bq.partitionPredicate(ctx); partition != "" {
+		queryConditions = append(queryConditions, partition)
+	}
 
-	for _, condition := range conditions.Conditions {
-		conditionString := condition.Field + " " + condition.Clause + " @when_" + condition.Field
-		queryConditions = append(queryConditions, conditionString)
-		values = append(values, bigquery.QueryParameter{Name: "when_" + condition.Field, Value: types.ReformatValue(condition.Value)})
+	var values []bigquery.QueryParameter
+	if conditions != nil {
+		for _, condition := range conditions.Conditions {
+			conditionString := condition.Field + " " + condition.Clause + " @when_" + condition.Field
+			queryConditions = append(queryConditions, conditionString)
+			values = append(values, bigquery.QueryParameter{Name: "when_" + condition.Field, Value: types.ReformatValue(condition.Value)})
+		}
 	}
 
-	return strings.Join(queryConditions, " "+conditions.JoinCondition+" "), values
+	joinCondition := "AND"
+	if conditions != nil && conditions.JoinCondition != "" {
+		joinCondition = conditions.JoinCondition
+	}
+	return strings.Join(queryConditions, " "+joinCondition+" "), values, nil
 }
 func (bq *BigQuery) Delete(ctx context.Context, tableName string, deleteConditions *WhenConditions) (err error) {
-	whenCondition, values := bq.toWhenConditions(deleteConditions)
+	whenCondition, values, err := bq.toWhenConditions(ctx, deleteConditions)
+	if err != nil {
+		return err
+	}
 	if len(whenCondition) == 0 {
 		return errors.New("delete conditions are empty")
 	}
@@ -711,17 +1958,8 @@ func (bq *BigQuery) Delete(ctx context.Context, tableName string, deleteConditio
 		}
 	}()
 
-	query := bq.client.Query(deleteQuery)
-	query.Parameters = values
-	job, err := query.Run(ctx)
-	if err != nil {
-		return err
-	}
-	status, err := job.Wait(ctx)
-	if err != nil {
-		return err
-	}
-	return status.Err()
+	_, err = bq.runQueryJobWithRetry(ctx, "delete", deleteQuery, values)
+	return err
 }
 func (bq *BigQuery) Type() string {
 	return "bigquery"
@@ -0,0 +1,194 @@
+package sql
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/storage"
+	"github.com/jitsucom/bulker/implementations"
+	"github.com/jitsucom/bulker/types"
+)
+
+// gcsClientPool is a fixed set of GCS clients shared by every stream on a BigQuery adapter (see
+// BigQuery.storagePool), letting insertViaLoadJobPooled shard a batch across them and upload the shards
+// concurrently instead of staging one object at a time through bq.storageClient. Queued and in-flight
+// byte counts are exposed via QueueDepth/InFlightBytes; wiring them into Prometheus needs a metrics
+// registry this snapshot doesn't vendor, so callers that have one can poll these directly for now.
+type gcsClientPool struct {
+	clients       []*storage.Client
+	queueDepth    int64
+	inFlightBytes int64
+}
+
+// newGCSClientPool opens size GCS clients using the same credentials NewBigquery itself would use.
+func newGCSClientPool(ctx context.Context, config *implementations.GoogleConfig, size int) (*gcsClientPool, error) {
+	clients := make([]*storage.Client, size)
+	for i := range clients {
+		var (
+			c   *storage.Client
+			err error
+		)
+		if config.Credentials == nil {
+			c, err = storage.NewClient(ctx)
+		} else {
+			c, err = storage.NewClient(ctx, config.Credentials)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCS client %d/%d for storage pool: %w", i+1, size, err)
+		}
+		clients[i] = c
+	}
+	return &gcsClientPool{clients: clients}, nil
+}
+
+func (p *gcsClientPool) client(i int) *storage.Client {
+	return p.clients[i%len(p.clients)]
+}
+
+// QueueDepth reports how many shard uploads are currently queued or in flight against this pool.
+func (p *gcsClientPool) QueueDepth() int64 { return atomic.LoadInt64(&p.queueDepth) }
+
+// InFlightBytes reports how many shard bytes are currently queued or in flight against this pool.
+func (p *gcsClientPool) InFlightBytes() int64 { return atomic.LoadInt64(&p.inFlightBytes) }
+
+// storagePool lazily builds and caches a gcsClientPool of n clients the first time a stream requests
+// pooling; later streams, even if they ask for a different n, share the pool already built. n <= 1
+// disables pooling (returns nil, nil).
+func (bq *BigQuery) storagePool(ctx context.Context, n int) (*gcsClientPool, error) {
+	if n <= 1 {
+		return nil, nil
+	}
+	bq.gcsPoolMu.Lock()
+	defer bq.gcsPoolMu.Unlock()
+	if bq.gcsPool != nil {
+		return bq.gcsPool, nil
+	}
+	pool, err := newGCSClientPool(ctx, bq.config, n)
+	if err != nil {
+		return nil, err
+	}
+	bq.gcsPool = pool
+	return pool, nil
+}
+
+// insertViaLoadJobPooled is insertViaLoadJob's sharded path: it stages objects across pool's clients
+// concurrently (stageNDJSONPooled) and then runs a single load job against a wildcard GCS URI covering
+// every shard, instead of one load job per chunk.
+func (bq *BigQuery) insertViaLoadJobPooled(ctx context.Context, table *Table, objects []types.Object, pool *gcsClientPool, disposition bigquery.TableWriteDisposition) error {
+	prefix, objectNames, err := bq.stageNDJSONPooled(ctx, pool, table.Name, objects)
+	if err != nil {
+		return fmt.Errorf("failed to stage objects in GCS via storage pool: %w", err)
+	}
+	defer func() {
+		for _, objectName := range objectNames {
+			_ = bq.storageClient.Bucket(bq.config.Bucket).Object(objectName).Delete(context.Background())
+		}
+	}()
+
+	bqTable := bq.client.Dataset(bq.config.Dataset).Table(table.Name)
+	gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s*", bq.config.Bucket, prefix))
+	gcsRef.SourceFormat = bigquery.JSON
+	loader := bqTable.LoaderFrom(gcsRef)
+	loader.CreateDisposition = bigquery.CreateNever
+	loader.WriteDisposition = disposition
+	loader.JobID = bqJobID("load_pooled")
+
+	if err := bq.runLoadJobWithRetry(ctx, true, loader); err != nil {
+		return fmt.Errorf("failed to run pooled load job for %d shard(s): %w", len(objectNames), err)
+	}
+	return nil
+}
+
+// stageNDJSONPooled shards objects across pool's clients roughly evenly by count and uploads each shard
+// concurrently as its own gzip'd newline-delimited JSON object under a shared prefix, so
+// insertViaLoadJobPooled can reference every shard with a single wildcard GCS URI. Each upload runs
+// under its own context derived from ctx, so one lane being canceled doesn't affect the others.
+func (bq *BigQuery) stageNDJSONPooled(ctx context.Context, pool *gcsClientPool, tableName string, objects []types.Object) (prefix string, objectNames []string, err error) {
+	n := len(pool.clients)
+	prefix = fmt.Sprintf("%s/%s_%d", bigQueryStageObjectDir, tableName, time.Now().UnixNano())
+
+	shards := make([][]types.Object, n)
+	for i, object := range objects {
+		shards[i%n] = append(shards[i%n], object)
+	}
+
+	type shardUpload struct {
+		index      int
+		objectName string
+		objects    []types.Object
+	}
+	var uploads []shardUpload
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		uploads = append(uploads, shardUpload{index: i, objectName: fmt.Sprintf("%s_part%d.ndjson.gz", prefix, i), objects: shard})
+		objectNames = append(objectNames, uploads[len(uploads)-1].objectName)
+	}
+
+	atomic.AddInt64(&pool.queueDepth, int64(len(uploads)))
+	defer atomic.AddInt64(&pool.queueDepth, -int64(len(uploads)))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, u := range uploads {
+		wg.Add(1)
+		go func(u shardUpload) {
+			defer wg.Done()
+			uploadCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			if uploadErr := bq.uploadNDJSONShard(uploadCtx, pool, pool.client(u.index), u.objectName, u.objects); uploadErr != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = uploadErr
+				}
+				mu.Unlock()
+			}
+		}(u)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return "", nil, firstErr
+	}
+	return prefix, objectNames, nil
+}
+
+// uploadNDJSONShard gzip's objects as newline-delimited JSON directly into objectName via client,
+// tracking the written bytes on pool's InFlightBytes counter for the duration of the upload.
+func (bq *BigQuery) uploadNDJSONShard(ctx context.Context, pool *gcsClientPool, client *storage.Client, objectName string, objects []types.Object) error {
+	writer := client.Bucket(bq.config.Bucket).Object(objectName).NewWriter(ctx)
+	gzWriter := gzip.NewWriter(writer)
+	var written int64
+	defer func() { atomic.AddInt64(&pool.inFlightBytes, -written) }()
+
+	for _, object := range objects {
+		line, err := json.Marshal(object)
+		if err != nil {
+			_ = gzWriter.Close()
+			_ = writer.Close()
+			return err
+		}
+		line = append(line, '\n')
+		n, err := gzWriter.Write(line)
+		written += int64(n)
+		atomic.AddInt64(&pool.inFlightBytes, int64(n))
+		if err != nil {
+			_ = gzWriter.Close()
+			_ = writer.Close()
+			return err
+		}
+	}
+	if err := gzWriter.Close(); err != nil {
+		return err
+	}
+	return writer.Close()
+}
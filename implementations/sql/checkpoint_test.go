@@ -0,0 +1,51 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResumeStreamRestoresCheckpointedState(t *testing.T) {
+	store := NewInMemoryCheckpointStore()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if err := store.SaveCheckpoint(ctx, Checkpoint{
+		StreamID:      "stream-1",
+		TableName:     "events",
+		BatchID:       "batch-1",
+		LastOffset:    42,
+		ProcessedRows: 42,
+		UpdatedAt:     now,
+	}); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+
+	ps, ok, err := ResumeStream(ctx, store, &stubInsertAdapter{}, nil, "", "stream-1")
+	if err != nil {
+		t.Fatalf("ResumeStream: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected ResumeStream to find the checkpoint")
+	}
+	if ps.tableName != "events" {
+		t.Fatalf("expected resumed stream's tableName to be events, got %q", ps.tableName)
+	}
+	if ps.batchID != "batch-1" {
+		t.Fatalf("expected resumed stream's batchID to be batch-1, got %q", ps.batchID)
+	}
+	if ps.state.ProcessedRows != 42 {
+		t.Fatalf("expected resumed stream's ProcessedRows to be 42, got %d", ps.state.ProcessedRows)
+	}
+}
+
+func TestResumeStreamNoCheckpointReturnsFalse(t *testing.T) {
+	store := NewInMemoryCheckpointStore()
+	ps, ok, err := ResumeStream(context.Background(), store, &stubInsertAdapter{}, nil, "", "never-checkpointed")
+	if err != nil {
+		t.Fatalf("ResumeStream: %v", err)
+	}
+	if ok || ps != nil {
+		t.Fatalf("expected ResumeStream to report no checkpoint found, got ok=%v ps=%v", ok, ps)
+	}
+}
@@ -3,20 +3,31 @@ package sql
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	_ "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2"
+	chdriver "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/jitsucom/bulker/base/errorj"
 	"github.com/jitsucom/bulker/base/logging"
 	"github.com/jitsucom/bulker/base/utils"
 	"github.com/jitsucom/bulker/bulker"
 	"github.com/jitsucom/bulker/types"
+	"github.com/shopspring/decimal"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 )
 
@@ -40,18 +51,54 @@ const (
 	//chDeleteQueryTemplate            = `DELETE FROM %s %s WHERE %s`
 	chDeleteQueryTemplate = `ALTER TABLE %s %s DELETE WHERE %s`
 
-	chCreateTableTemplate   = `CREATE TABLE %s %s (%s) %s %s %s %s`
-	chDropTableTemplate     = `DROP TABLE %s%s %s`
-	chTruncateTableTemplate = `TRUNCATE TABLE IF EXISTS %s %s`
-	chExchangeTableTemplate = `EXCHANGE TABLES %s AND %s %s`
-	chRenameTableTemplate   = `RENAME TABLE %s TO %s %s`
+	chInsertStatement          = `INSERT INTO %s (%s)`
+	chCopyTableTemplate        = `INSERT INTO %s (%s) SELECT %s FROM %s`
+	chImportFromSelectTemplate = `INSERT INTO %s (%s) SELECT %s FROM (%s)`
+	chOptimizeTableTemplate    = `OPTIMIZE TABLE %s %s %s FINAL DEDUPLICATE BY %s`
+
+	// chMergeModeReplacing/chMergeModeCollapsing/chMergeModeAggregating are the EngineConfig.MergeMode
+	// values CopyTables understands.
+	chMergeModeReplacing   = "replacing"
+	chMergeModeCollapsing  = "collapsing"
+	chMergeModeAggregating = "aggregating"
+
+	// EngineTypeMergeTree and friends are the EngineConfig.Type values NewTableStatementFactory composes
+	// into the table's ENGINE clause. EngineTypeMergeTree is plain, append-only MergeTree() with no
+	// deduplication on merge; the rest mirror ClickHouse's own MergeTree family members.
+	EngineTypeMergeTree           EngineType = "merge_tree"
+	EngineTypeReplacing           EngineType = "replacing"
+	EngineTypeSumming             EngineType = "summing"
+	EngineTypeAggregating         EngineType = "aggregating"
+	EngineTypeCollapsing          EngineType = "collapsing"
+	EngineTypeVersionedCollapsing EngineType = "versioned_collapsing"
+
+	chCreateTableTemplate = `CREATE TABLE %s %s (%s) %s %s %s %s %s %s %s`
+	// chCreateTableAsSelectTemplate materializes a raw SELECT into a new table, in place of the usual
+	// column list: tableName, onClusterClause, engineStatement, partitionClause, orderByClause,
+	// primaryKeyClause, selectSQL.
+	chCreateTableAsSelectTemplate = `CREATE TABLE %s %s %s %s %s %s AS %s`
+	chDropTableTemplate           = `DROP TABLE %s%s %s`
+	chTruncateTableTemplate       = `TRUNCATE TABLE IF EXISTS %s %s`
+	chExchangeTableTemplate       = `EXCHANGE TABLES %s AND %s %s`
+	chRenameTableTemplate         = `RENAME TABLE %s TO %s %s`
+
+	chFreezeTableTemplate   = `ALTER TABLE %s %s FREEZE WITH NAME '%s'`
+	chUnfreezeTableTemplate = `ALTER TABLE %s %s UNFREEZE WITH NAME '%s'`
 
 	chSelectFinalStatement = `SELECT %s FROM %s FINAL %s%s`
-	chLoadStatement        = `INSERT INTO %s (%s) VALUES (%s)`
 
 	chDefaultPartition  = ``
 	chDefaultOrderBy    = `ORDER BY (id)`
 	chDefaultPrimaryKey = ``
+
+	// chDefaultReplicationPath/chDefaultReplicaName are EngineConfig.ReplicationPath/ReplicaName's
+	// fallback when neither is configured, matching this adapter's previous hardcoded behavior.
+	chDefaultReplicationPath = `/clickhouse/tables/{shard}/{database}/{table}`
+	chDefaultReplicaName     = `{replica}`
+
+	// chDefaultBatchSize is how many rows the native PrepareBatch/Append/Send path buffers per
+	// batch.Send() call when the caller didn't set WithBatchSize.
+	chDefaultBatchSize = 10000
 )
 
 var (
@@ -110,8 +157,97 @@ type ClickHouseConfig struct {
 	TLS      map[string]string `mapstructure:"tls,omitempty" json:"tls,omitempty" yaml:"tls,omitempty"`
 	Cluster  string            `mapstructure:"cluster,omitempty" json:"cluster,omitempty" yaml:"cluster,omitempty"`
 	Engine   *EngineConfig     `mapstructure:"engine,omitempty" json:"engine,omitempty" yaml:"engine,omitempty"`
+
+	// Shards, when non-empty, makes Insert/LoadTable write directly to each shard's local table
+	// (routing each row by hashing the ShardingKey column) instead of relying on a single DSN that
+	// fronts the cluster. Requires native (non-HTTP) DSNs. Leave empty to keep the existing single-DSN
+	// behavior.
+	Shards      []ShardConfig `mapstructure:"shards,omitempty" json:"shards,omitempty" yaml:"shards,omitempty"`
+	ShardingKey string        `mapstructure:"sharding_key,omitempty" json:"sharding_key,omitempty" yaml:"sharding_key,omitempty"`
+
+	// DefaultCodec is the CODEC(...) clause applied to columns with no per-column entry in
+	// Engine.Columns. Empty (the default) lets ClickHouse pick its own default codec.
+	DefaultCodec string `mapstructure:"default_codec,omitempty" json:"default_codec,omitempty" yaml:"default_codec,omitempty"`
+
+	// HTTPSettings configures async_insert and insert deduplication for Insert/LoadTable. Leave nil to
+	// keep ClickHouse's own server-side defaults (synchronous insert, no deduplication token).
+	HTTPSettings *HTTPSettings `mapstructure:"http_settings,omitempty" json:"http_settings,omitempty" yaml:"http_settings,omitempty"`
+
+	// Snapshot, when Enabled, makes ReplaceTable FREEZE the table it's about to swap out before running
+	// EXCHANGE TABLES, giving the operator a rollback path (ATTACH PARTITION from the frozen shadow
+	// directory) if the swap or whatever produced the replacement table turns out to be bad.
+	Snapshot *SnapshotConfig `mapstructure:"snapshot,omitempty" json:"snapshot,omitempty" yaml:"snapshot,omitempty"`
+}
+
+// SnapshotConfig controls the FREEZE-based checkpoint ReplaceTable takes before swapping in a
+// replacement table. See ClickHouseConfig.Snapshot.
+type SnapshotConfig struct {
+	Enabled bool `mapstructure:"enabled,omitempty" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// Sink, if set, is the name a SnapshotSink was registered under via RegisterSnapshotSink; its Upload
+	// is called with the frozen shadow/<name>/ directory right after FREEZE succeeds. Leave empty to
+	// FREEZE only, relying on the local data directory for the rollback path.
+	Sink   string `mapstructure:"sink,omitempty" json:"sink,omitempty" yaml:"sink,omitempty"`
+	Bucket string `mapstructure:"bucket,omitempty" json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	// Retention is how long a FREEZE snapshot (and its Sink upload, if any) should be kept before an
+	// operator-run cleanup job purges it. Nothing in this package enforces it yet; it's read by whatever
+	// external pruning job the deployment wires up.
+	Retention time.Duration `mapstructure:"retention,omitempty" json:"retention,omitempty" yaml:"retention,omitempty"`
+}
+
+// SnapshotSink uploads a FREEZE'd table's shadow directory to an object store. Implementations register
+// themselves with RegisterSnapshotSink under the name SnapshotConfig.Sink refers to; this package ships
+// no implementations of its own.
+type SnapshotSink interface {
+	// Upload copies everything under shadowPath (a "shadow/<name>/" directory FREEZE produced on local
+	// disk) to bucket.
+	Upload(ctx context.Context, shadowPath string, bucket string) error
+}
+
+var snapshotSinks = map[string]SnapshotSink{}
+
+// RegisterSnapshotSink makes sink available as a ClickHouseConfig.Snapshot.Sink value.
+func RegisterSnapshotSink(name string, sink SnapshotSink) {
+	snapshotSinks[name] = sink
+}
+
+// HTTPSettings are the async_insert family of ClickHouse query settings, applied to every Insert/
+// LoadTable call regardless of transport: as URL query parameters over HTTP, and via
+// clickhouse.Context(ctx, clickhouse.WithSettings(...)) over the native PrepareBatch/Append/Send path.
+type HTTPSettings struct {
+	// AsyncInsert buffers the insert server-side and returns before the data is necessarily on disk,
+	// trading durability for much higher insert throughput under many small concurrent inserts.
+	AsyncInsert bool `mapstructure:"async_insert,omitempty" json:"async_insert,omitempty" yaml:"async_insert,omitempty"`
+	// WaitForAsyncInsert blocks the Insert/LoadTable call until the async buffer this row landed in has
+	// actually been flushed, so a successful return still means the data is durable. Ignored unless
+	// AsyncInsert is true.
+	WaitForAsyncInsert bool `mapstructure:"wait_for_async_insert,omitempty" json:"wait_for_async_insert,omitempty" yaml:"wait_for_async_insert,omitempty"`
+	// AsyncInsertMaxDataSize caps how many bytes ClickHouse buffers per async_insert flush, in bytes.
+	// Zero leaves ClickHouse's own default.
+	AsyncInsertMaxDataSize int64 `mapstructure:"async_insert_max_data_size,omitempty" json:"async_insert_max_data_size,omitempty" yaml:"async_insert_max_data_size,omitempty"`
+	// InsertDeduplicationToken is a text/template string rendered once per Insert/LoadTable call, with
+	// "{{.stream_id}}" and "{{.batch_id}}" available, and passed to ClickHouse as
+	// insert_deduplication_token so a retried call that resends the same batch is deduplicated
+	// server-side instead of double-inserting. Empty (the default) leaves deduplication off.
+	InsertDeduplicationToken string `mapstructure:"insert_deduplication_token,omitempty" json:"insert_deduplication_token,omitempty" yaml:"insert_deduplication_token,omitempty"`
+	// AsyncInsertBusyTimeoutMs caps how long ClickHouse waits before flushing a not-yet-full async_insert
+	// buffer, in milliseconds. Zero leaves ClickHouse's own default. Ignored unless AsyncInsert is true.
+	AsyncInsertBusyTimeoutMs int64 `mapstructure:"async_insert_busy_timeout_ms,omitempty" json:"async_insert_busy_timeout_ms,omitempty" yaml:"async_insert_busy_timeout_ms,omitempty"`
+	// FallbackToSync retries an HTTP insert without any async_insert settings when the server's response
+	// indicates async_insert isn't available (e.g. disabled server-side or an unrecognized setting on
+	// older versions), instead of failing the whole insert outright.
+	FallbackToSync bool `mapstructure:"fallback_to_sync,omitempty" json:"fallback_to_sync,omitempty" yaml:"fallback_to_sync,omitempty"`
 }
 
+// ShardConfig describes one cluster shard's replica DSNs (tried in order, failing over on connect
+// error) and its relative routing weight for ClickHouseConfig.Shards.
+type ShardConfig struct {
+	Dsns   []string `mapstructure:"dsns,omitempty" json:"dsns,omitempty" yaml:"dsns,omitempty"`
+	Weight int      `mapstructure:"weight,omitempty" json:"weight,omitempty" yaml:"weight,omitempty"`
+}
+
+// EngineType selects which MergeTree family member a table uses. See EngineConfig.Type.
+type EngineType string
+
 // EngineConfig dto for deserialized clickhouse engine config
 type EngineConfig struct {
 	RawStatement    string        `mapstructure:"raw_statement,omitempty" json:"raw_statement,omitempty" yaml:"raw_statement,omitempty"`
@@ -119,6 +255,59 @@ type EngineConfig struct {
 	PartitionFields []FieldConfig `mapstructure:"partition_fields,omitempty" json:"partition_fields,omitempty" yaml:"partition_fields,omitempty"`
 	OrderFields     []FieldConfig `mapstructure:"order_fields,omitempty" json:"order_fields,omitempty" yaml:"order_fields,omitempty"`
 	PrimaryKeys     []string      `mapstructure:"primary_keys,omitempty" json:"primary_keys,omitempty" yaml:"primary_keys,omitempty"`
+
+	// MergeMode selects the upsert semantics CopyTables uses when merge=true: "replacing" pairs with a
+	// ReplacingMergeTree(VersionColumn) engine, "collapsing" with CollapsingMergeTree(SignColumn), and
+	// "aggregating" leaves row reconciliation to AggregatingMergeTree's own merge functions (neither
+	// column is injected). Empty (the default) means CopyTables ignores merge and always does a plain
+	// insert-select, as before this field existed.
+	MergeMode     string `mapstructure:"merge_mode,omitempty" json:"merge_mode,omitempty" yaml:"merge_mode,omitempty"`
+	VersionColumn string `mapstructure:"version_column,omitempty" json:"version_column,omitempty" yaml:"version_column,omitempty"`
+	SignColumn    string `mapstructure:"sign_column,omitempty" json:"sign_column,omitempty" yaml:"sign_column,omitempty"`
+
+	// Columns maps a column name to per-column storage hints (compression codec, LowCardinality
+	// wrapping) that CreateTable/PatchTableSchema's DDL honors. A column absent from this map falls
+	// back to ClickHouseConfig.DefaultCodec and no LowCardinality wrapping.
+	Columns map[string]ColumnEngineOptions `mapstructure:"columns,omitempty" json:"columns,omitempty" yaml:"columns,omitempty"`
+
+	// Type selects the MergeTree family member NewTableStatementFactory puts in the table's ENGINE
+	// clause (and its Replicated* counterpart when Cluster is set). Empty defaults to EngineTypeReplacing,
+	// this adapter's previous hardcoded behavior.
+	Type EngineType `mapstructure:"type,omitempty" json:"type,omitempty" yaml:"type,omitempty"`
+	// SummingColumns lists the columns EngineTypeSumming sums on merge. Empty lets SummingMergeTree sum
+	// every numeric column not part of the ORDER BY key, ClickHouse's own default.
+	SummingColumns []string `mapstructure:"summing_columns,omitempty" json:"summing_columns,omitempty" yaml:"summing_columns,omitempty"`
+
+	// ReplicationPath templates the ZooKeeper path a ReplicatedReplacingMergeTree table registers its
+	// metadata under. "{database}" and "{table}" are substituted here; "{shard}", "{replica}", "{layer}"
+	// and "{uuid}" are left untouched for ClickHouse's own server-side macro substitution (the last two
+	// require no config on our side: {uuid} is resolved from the table's own Atomic-engine UUID).
+	// Must reference "{table}" or "{uuid}" so distinct tables can never collide on the same znode. Empty
+	// (the default) keeps the previous hardcoded "/clickhouse/tables/{shard}/<database>/{table}".
+	ReplicationPath string `mapstructure:"replication_path,omitempty" json:"replication_path,omitempty" yaml:"replication_path,omitempty"`
+	// ReplicaName templates the replica identifier registered alongside ReplicationPath, with the same
+	// substitutions available. Empty (the default) keeps the previous hardcoded "{replica}".
+	ReplicaName string `mapstructure:"replica_name,omitempty" json:"replica_name,omitempty" yaml:"replica_name,omitempty"`
+
+	// TableTTL is a table-level TTL expression, e.g. "created_at + INTERVAL 90 DAY DELETE" or
+	// "created_at + INTERVAL 30 DAY TO VOLUME 'cold'". Emitted as "TTL <TableTTL>" after ORDER BY/
+	// PRIMARY KEY. Empty (the default) omits the clause, leaving rows to live forever.
+	TableTTL string `mapstructure:"table_ttl,omitempty" json:"table_ttl,omitempty" yaml:"table_ttl,omitempty"`
+	// SampleBy is a SAMPLE BY expression, which must be part of (or a function of) the ORDER BY key.
+	// Empty (the default) omits the clause.
+	SampleBy string `mapstructure:"sample_by,omitempty" json:"sample_by,omitempty" yaml:"sample_by,omitempty"`
+	// Settings is emitted verbatim as a SETTINGS k=v, ... clause, e.g. {"index_granularity": "8192"}.
+	Settings map[string]string `mapstructure:"settings,omitempty" json:"settings,omitempty" yaml:"settings,omitempty"`
+}
+
+// ColumnEngineOptions are the per-column storage hints declared under EngineConfig.Columns.
+type ColumnEngineOptions struct {
+	// Codec is a ClickHouse CODEC(...) body, e.g. "ZSTD(3)" or "Delta, LZ4". Applied verbatim, without
+	// validation, as CODEC(<Codec>) after the column's type.
+	Codec string `mapstructure:"codec,omitempty" json:"codec,omitempty" yaml:"codec,omitempty"`
+	// LowCardinality wraps the column's DDL type in LowCardinality(...), best suited to columns with a
+	// small number of distinct values.
+	LowCardinality bool `mapstructure:"low_cardinality,omitempty" json:"low_cardinality,omitempty" yaml:"low_cardinality,omitempty"`
 }
 
 // FieldConfig dto for deserialized clickhouse engine fields
@@ -132,6 +321,26 @@ type ClickHouse struct {
 	SQLAdapterBase[ClickHouseConfig]
 	httpMode              bool
 	tableStatementFactory *TableStatementFactory
+	// nativeConn is opened against the same DSN as dataSource, but via clickhouse-go v2's native driver
+	// interface, so Insert/LoadTable can use PrepareBatch/Append/Send instead of a row-at-a-time prepared
+	// statement. Only opened for native (non-HTTP) DSNs; nil when httpMode is true.
+	nativeConn chdriver.Conn
+	// shards holds one native connection per ClickHouseConfig.Shards entry (first reachable replica),
+	// used by Insert/LoadTable to write straight to each shard's local table instead of going through a
+	// single DSN. Empty unless ClickHouseConfig.Shards is configured.
+	shards []chShard
+
+	// schemaCache holds GetTableSchema results keyed by table name, shared across every stream this
+	// adapter instance opens; CreateTable and PatchTableSchema invalidate a table's entry whenever they
+	// change its shape. See table_schema_cache.go.
+	schemaCache *tableSchemaCache
+}
+
+// chShard pairs a shard's routing weight with the native connection opened against its first
+// reachable replica DSN.
+type chShard struct {
+	conn   chdriver.Conn
+	weight int
 }
 
 // NewClickHouse returns configured ClickHouse adapter instance
@@ -176,6 +385,47 @@ func NewClickHouse(bulkerConfig bulker.Config) (bulker.Bulker, error) {
 		return nil, err
 	}
 
+	var nativeConn chdriver.Conn
+	if !httpMode {
+		nativeOpts, err := clickhouse.ParseDSN(config.Dsns[0])
+		if err != nil {
+			dataSource.Close()
+			return nil, fmt.Errorf("failed to parse clickhouse native dsn: %w", err)
+		}
+		nativeConn, err = clickhouse.Open(nativeOpts)
+		if err != nil {
+			dataSource.Close()
+			return nil, fmt.Errorf("failed to open clickhouse native connection: %w", err)
+		}
+	}
+
+	var shards []chShard
+	if len(config.Shards) > 0 {
+		if httpMode {
+			dataSource.Close()
+			if nativeConn != nil {
+				_ = nativeConn.Close()
+			}
+			return nil, fmt.Errorf("clickhouse: shards is only supported with native (non-HTTP) dsns")
+		}
+		for i, shardConfig := range config.Shards {
+			conn, err := chOpenShard(shardConfig)
+			if err != nil {
+				dataSource.Close()
+				_ = nativeConn.Close()
+				for _, s := range shards {
+					_ = s.conn.Close()
+				}
+				return nil, fmt.Errorf("failed to open shard %d: %w", i, err)
+			}
+			weight := shardConfig.Weight
+			if weight <= 0 {
+				weight = 1
+			}
+			shards = append(shards, chShard{conn: conn, weight: weight})
+		}
+	}
+
 	tableStatementFactory, err := NewTableStatementFactory(config)
 	if err != nil {
 		return nil, err
@@ -185,11 +435,13 @@ func NewClickHouse(bulkerConfig bulker.Config) (bulker.Bulker, error) {
 		return fmt.Sprintf("%s", tableName)
 	}
 	var nullableFields []string
+	var columnOptions map[string]ColumnEngineOptions
 	if config.Engine != nil {
 		nullableFields = config.Engine.NullableFields
+		columnOptions = config.Engine.Columns
 	}
 	columnDDlFunc := func(name string, column SQLColumn, pkFields utils.Set[string]) string {
-		return chColumnDDL(name, column, pkFields, nullableFields)
+		return chColumnDDL(name, column, pkFields, nullableFields, columnOptions[name], config.DefaultCodec)
 	}
 	queryLogger := logging.NewQueryLogger(bulkerConfig.Id, os.Stderr, os.Stderr)
 	sqlAdapterBase := newSQLAdapterBase(ClickHouseBulkerTypeId, config, dataSource,
@@ -200,11 +452,38 @@ func NewClickHouse(bulkerConfig bulker.Config) (bulker.Bulker, error) {
 		SQLAdapterBase:        sqlAdapterBase,
 		tableStatementFactory: tableStatementFactory,
 		httpMode:              httpMode,
+		nativeConn:            nativeConn,
+		shards:                shards,
+		schemaCache:           newTableSchemaCache(0),
 	}
 
 	return c, nil
 }
 
+// chOpenShard opens a native connection against the first reachable replica DSN in shardConfig.Dsns,
+// failing over to the next replica on connect/ping error.
+func chOpenShard(shardConfig ShardConfig) (chdriver.Conn, error) {
+	var lastErr error
+	for _, dsn := range shardConfig.Dsns {
+		opts, err := clickhouse.ParseDSN(dsn)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		conn, err := clickhouse.Open(opts)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := conn.Ping(context.Background()); err != nil {
+			lastErr = err
+			continue
+		}
+		return conn, nil
+	}
+	return nil, fmt.Errorf("no reachable replica (last error: %w)", lastErr)
+}
+
 func (ch *ClickHouse) CreateStream(id, tableName string, mode bulker.BulkMode, streamOptions ...bulker.StreamOption) (bulker.BulkerStream, error) {
 	streamOptions = append(streamOptions, withLocalBatchFile(fmt.Sprintf("bulker_%s_stream_%s_%s", mode, tableName, utils.SanitizeString(id))))
 
@@ -253,7 +532,12 @@ func (ch *ClickHouse) InitDatabase(ctx context.Context) error {
 
 // CreateTable create database table with name,columns provided in Table representation
 // New tables will have MergeTree() or ReplicatedMergeTree() engine depends on config.cluster empty or not
-func (ch *ClickHouse) CreateTable(ctx context.Context, table *Table) error {
+func (ch *ClickHouse) CreateTable(ctx context.Context, table *Table) (err error) {
+	defer func() {
+		if err == nil {
+			ch.schemaCache.Invalidate(table.Name)
+		}
+	}()
 	columns := table.SortedColumnNames()
 	columnsDDL := make([]string, len(columns))
 	for i, columnName := range table.SortedColumnNames() {
@@ -282,8 +566,44 @@ func (ch *ClickHouse) CreateTable(ctx context.Context, table *Table) error {
 	return nil
 }
 
-// GetTableSchema return table (name,columns with name and types) representation wrapped in Table struct
+// CreateTableAsSelect materializes selectSQL server-side into a new table named tableName, for "sql
+// model" sources that define their output as a raw query instead of a stream of rows. engineOverrides
+// lets the model choose its own PartitionFields/OrderFields/PrimaryKeys/Type; leave it nil to use this
+// adapter's configured table defaults (same as CreateTable). As with CreateTable, a matching Distributed
+// wrapper is created afterward when config.Cluster is set.
+func (ch *ClickHouse) CreateTableAsSelect(ctx context.Context, tableName, selectSQL string, engineOverrides *EngineConfig) error {
+	statementStr, err := ch.tableStatementFactory.CreateTableAsSelectStatement(tableName, selectSQL, engineOverrides)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ch.txOrDb(ctx).ExecContext(ctx, statementStr); err != nil {
+		return errorj.CreateTableError.Wrap(err, "failed to create table as select").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Database:  ch.config.Database,
+				Cluster:   ch.config.Cluster,
+				Table:     tableName,
+				Statement: statementStr,
+			})
+	}
+
+	if ch.config.Cluster != "" {
+		return ch.createDistributedTableInTransaction(ctx, tableName)
+	}
+
+	return nil
+}
+
+// GetTableSchema return table (name,columns with name and types) representation wrapped in Table struct.
+// Note: this does not parse system.columns.compression_codec back into the result, so
+// ColumnEngineOptions.Codec/LowCardinality drift (an ADD/re-alter the user made outside EngineConfig)
+// isn't detected here the way PatchTableSchema otherwise compares declared vs. actual columns; SQLColumn
+// has no field to carry that back to the caller.
 func (ch *ClickHouse) GetTableSchema(ctx context.Context, tableName string) (*Table, error) {
+	if cached, ok := ch.schemaCache.Get(tableName); ok {
+		return cached, nil
+	}
+
 	table := &Table{Name: tableName, Columns: Columns{}, PKFields: utils.NewSet[string]()}
 	rows, err := ch.txOrDb(ctx).QueryContext(ctx, chTableSchemaQuery, ch.config.Database, tableName)
 	if err != nil {
@@ -327,15 +647,21 @@ func (ch *ClickHouse) GetTableSchema(ctx context.Context, tableName string) (*Ta
 			})
 	}
 
+	ch.schemaCache.Put(tableName, table)
 	return table, nil
 }
 
 // PatchTableSchema add new columns(from provided Table) to existing table
 // drop and create distributed table
-func (ch *ClickHouse) PatchTableSchema(ctx context.Context, patchSchema *Table) error {
+func (ch *ClickHouse) PatchTableSchema(ctx context.Context, patchSchema *Table) (err error) {
 	if len(patchSchema.Columns) == 0 {
 		return nil
 	}
+	defer func() {
+		if err == nil {
+			ch.schemaCache.Invalidate(patchSchema.Name)
+		}
+	}()
 	columns := patchSchema.SortedColumnNames()
 	addedColumnsDDL := make([]string, len(patchSchema.Columns))
 	for i, columnName := range columns {
@@ -390,34 +716,24 @@ func (ch *ClickHouse) Count(ctx context.Context, tableName string, whenCondition
 }
 
 func (ch *ClickHouse) Insert(ctx context.Context, targetTable *Table, merge bool, objects []types.Object) (err error) {
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeInsert != nil {
+		if objects, err = hooks.BeforeInsert(ctx, targetTable, objects); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		runOnError(ctx, hooks, "insert", err)
+		if err == nil && hooks.AfterInsert != nil {
+			hooks.AfterInsert(ctx, targetTable, InsertStats{Objects: len(objects)})
+		}
+	}()
 	if ch.httpMode {
 		return ch.insert(ctx, targetTable, objects)
 	}
-	tx, err := ch.dataSource.BeginTx(ctx, nil)
-	if err != nil {
-		err = errorj.LoadError.Wrap(err, "failed to open transaction to load table").
-			WithProperty(errorj.DBInfo, &types.ErrorPayload{
-				Database:    ch.config.Database,
-				Cluster:     ch.config.Cluster,
-				Table:       targetTable.Name,
-				PrimaryKeys: targetTable.GetPKFields(),
-			})
-	}
-
-	columns := targetTable.SortedColumnNames()
-	columnNames := make([]string, len(columns))
-	placeHolders := make([]string, len(columns))
-
-	for i, name := range columns {
-		column := targetTable.Columns[name]
-		columnNames[i] = ch.columnName(name)
-		placeHolders[i] = ch.typecastFunc(ch.parameterPlaceholder(i, ch.columnName(name)), column)
-
-	}
-	copyStatement := fmt.Sprintf(chLoadStatement, ch.fullTableName(targetTable.Name), strings.Join(columnNames, ", "), strings.Join(placeHolders, ", "))
+	copyStatement := fmt.Sprintf(chInsertStatement, ch.fullTableName(targetTable.Name), strings.Join(ch.columnNamesForBatch(targetTable), ", "))
 	defer func() {
 		if err != nil {
-			_ = tx.Rollback()
 			err = errorj.ExecuteInsertError.Wrap(err, "failed to insert to table").
 				WithProperty(errorj.DBInfo, &types.ErrorPayload{
 					Database:    ch.config.Database,
@@ -428,31 +744,10 @@ func (ch *ClickHouse) Insert(ctx context.Context, targetTable *Table, merge bool
 				})
 		}
 	}()
-
-	stmt, err := tx.PrepareContext(ctx, copyStatement)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = stmt.Close()
-	}()
-
-	for _, object := range objects {
-		args := make([]any, len(columns))
-		for i, v := range columns {
-			l, err := convertType(object[v], targetTable.Columns[v])
-			if err != nil {
-				return err
-			}
-			//logging.Infof("%s: %v (%T) was %v", v, l, l, object[v])
-			args[i] = l
-		}
-		if _, err := stmt.ExecContext(ctx, args...); err != nil {
-			return checkErr(err)
-		}
+	if len(ch.shards) > 0 {
+		return ch.insertSharded(ctx, targetTable, copyStatement, objects)
 	}
-
-	return tx.Commit()
+	return ch.insertBatches(ctx, ch.nativeConn, targetTable, copyStatement, objectsIterator(objects))
 }
 
 // LoadTable transfer data from local file to ClickHouse table
@@ -463,31 +758,33 @@ func (ch *ClickHouse) LoadTable(ctx context.Context, targetTable *Table, loadSou
 	if loadSource.Format != ch.batchFileFormat {
 		return fmt.Errorf("LoadTable: only %s format is supported", ch.batchFileFormat)
 	}
-	tx, err := ch.dataSource.BeginTx(ctx, nil)
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeLoadTable != nil {
+		if err = hooks.BeforeLoadTable(ctx, targetTable, loadSource); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		runOnError(ctx, hooks, "loadTable", err)
+		if err == nil && hooks.AfterLoadTable != nil {
+			hooks.AfterLoadTable(ctx, targetTable)
+		}
+	}()
+	file, err := os.Open(loadSource.Path)
 	if err != nil {
-		err = errorj.LoadError.Wrap(err, "failed to open transaction to load table").
-			WithProperty(errorj.DBInfo, &types.ErrorPayload{
-				Database:    ch.config.Database,
-				Cluster:     ch.config.Cluster,
-				Table:       targetTable.Name,
-				PrimaryKeys: targetTable.GetPKFields(),
-			})
+		return err
 	}
+	defer func() {
+		_ = file.Close()
+	}()
 
-	columns := targetTable.SortedColumnNames()
-	columnNames := make([]string, len(columns))
-	placeHolders := make([]string, len(columns))
-
-	for i, name := range columns {
-		column := targetTable.Columns[name]
-		columnNames[i] = ch.columnName(name)
-		placeHolders[i] = ch.typecastFunc(ch.parameterPlaceholder(i, ch.columnName(name)), column)
-
+	if ch.httpMode {
+		return ch.insertFile(ctx, targetTable, file)
 	}
-	copyStatement := fmt.Sprintf(chLoadStatement, ch.fullTableName(targetTable.Name), strings.Join(columnNames, ", "), strings.Join(placeHolders, ", "))
+
+	copyStatement := fmt.Sprintf(chInsertStatement, ch.fullTableName(targetTable.Name), strings.Join(ch.columnNamesForBatch(targetTable), ", "))
 	defer func() {
 		if err != nil {
-			_ = tx.Rollback()
 			err = errorj.LoadError.Wrap(err, "failed to load table").
 				WithProperty(errorj.DBInfo, &types.ErrorPayload{
 					Database:    ch.config.Database,
@@ -498,59 +795,593 @@ func (ch *ClickHouse) LoadTable(ctx context.Context, targetTable *Table, loadSou
 				})
 		}
 	}()
+	return ch.insertBatches(ctx, ch.nativeConn, targetTable, copyStatement, fileRowSource(file))
+}
 
-	stmt, err := tx.PrepareContext(ctx, copyStatement)
-	if err != nil {
-		return err
+// columnNamesForBatch returns table's sorted column names, quoted/escaped the same way the row-at-a-time
+// INSERT statement used to, for use in a native INSERT INTO tbl (...) batch statement.
+func (ch *ClickHouse) columnNamesForBatch(table *Table) []string {
+	columns := table.SortedColumnNames()
+	names := make([]string, len(columns))
+	for i, name := range columns {
+		names[i] = ch.columnName(name)
 	}
-	defer func() {
-		_ = stmt.Close()
-	}()
-	//f, err := os.ReadFile(loadSource.Path)
-	//logging.Infof("FILE: %s", f)
+	return names
+}
 
-	file, err := os.Open(loadSource.Path)
-	if err != nil {
-		return err
+// chRowSource yields one decoded row at a time for a native batch insert. ok is false and err is nil
+// once the source is exhausted, so Insert (an in-memory slice) and LoadTable (an NDJSON batch file) can
+// share the same batching loop without either materializing the other's representation.
+type chRowSource func() (object map[string]any, ok bool, err error)
+
+func objectsIterator(objects []types.Object) chRowSource {
+	i := 0
+	return func() (map[string]any, bool, error) {
+		if i >= len(objects) {
+			return nil, false, nil
+		}
+		object := objects[i]
+		i++
+		return object, true, nil
 	}
+}
+
+func fileRowSource(file *os.File) chRowSource {
 	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
+	return func() (map[string]any, bool, error) {
+		if !scanner.Scan() {
+			return nil, false, scanner.Err()
+		}
 		object := map[string]any{}
 		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
 		decoder.UseNumber()
-		err = decoder.Decode(&object)
+		if err := decoder.Decode(&object); err != nil {
+			return nil, false, err
+		}
+		return object, true, nil
+	}
+}
+
+// insertBatches drains src into conn via PrepareBatch/Append/Send, chunked at BatchSizeFromContext(ctx)
+// rows (or chDefaultBatchSize when the stream didn't set WithBatchSize), reusing convertType for the
+// same per-column coercion the row-at-a-time prepared statement did. conn is ch.nativeConn for the
+// single-connection path, or one of ch.shards' connections when insertSharded is routing rows by shard.
+func (ch *ClickHouse) insertBatches(ctx context.Context, conn chdriver.Conn, targetTable *Table, insertStatement string, src chRowSource) error {
+	columns := targetTable.SortedColumnNames()
+	batchSize := BatchSizeFromContext(ctx)
+	if batchSize <= 0 {
+		batchSize = chDefaultBatchSize
+	}
+	ctx, err := ch.withHTTPSettings(ctx)
+	if err != nil {
+		return err
+	}
+	batch, err := conn.PrepareBatch(ctx, insertStatement)
+	if err != nil {
+		return err
+	}
+	rowsInBatch := 0
+	for {
+		object, ok, err := src()
 		if err != nil {
 			return err
 		}
+		if !ok {
+			break
+		}
 		args := make([]any, len(columns))
 		for i, v := range columns {
-			l, err := convertType(object[v], targetTable.Columns[v])
+			converted, err := convertType(object[v], targetTable.Columns[v])
 			if err != nil {
 				return err
 			}
-			//logging.Infof("%s: %v (%T) was %v", v, l, l, object[v])
-			args[i] = l
+			args[i] = converted
 		}
-		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		if err := batch.Append(args...); err != nil {
 			return checkErr(err)
 		}
+		rowsInBatch++
+		if rowsInBatch >= batchSize {
+			if err := batch.Send(); err != nil {
+				return checkErr(err)
+			}
+			if batch, err = conn.PrepareBatch(ctx, insertStatement); err != nil {
+				return err
+			}
+			rowsInBatch = 0
+		}
+	}
+	if rowsInBatch > 0 {
+		return checkErr(batch.Send())
+	}
+	return nil
+}
+
+// insertSharded partitions objects by ShardingKey and writes each shard's slice directly to its own
+// local table (ch.shards[i].conn) in parallel, instead of sending the whole batch through a single DSN.
+func (ch *ClickHouse) insertSharded(ctx context.Context, targetTable *Table, insertStatement string, objects []types.Object) error {
+	byShard := make([][]types.Object, len(ch.shards))
+	for _, object := range objects {
+		idx := ch.shardIndexFor(object)
+		byShard[idx] = append(byShard[idx], object)
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(ch.shards))
+	for i, shardObjects := range byShard {
+		if len(shardObjects) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, shardObjects []types.Object) {
+			defer wg.Done()
+			errs[i] = ch.insertBatches(ctx, ch.shards[i].conn, targetTable, insertStatement, objectsIterator(shardObjects))
+		}(i, shardObjects)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardIndexFor returns which ch.shards entry object routes to: hash(object[ShardingKey]) modulo the
+// shards' total weight, with weight acting as a repeat count over that 0-to-totalWeight range the way
+// ClickHouse's own weighted sharding works.
+func (ch *ClickHouse) shardIndexFor(object map[string]any) int {
+	totalWeight := 0
+	for _, s := range ch.shards {
+		totalWeight += s.weight
+	}
+	h := int(chShardHash(object[ch.config.ShardingKey]) % uint64(totalWeight))
+	acc := 0
+	for i, s := range ch.shards {
+		acc += s.weight
+		if h < acc {
+			return i
+		}
+	}
+	return len(ch.shards) - 1
+}
+
+// chShardHash hashes value's formatted string representation so rows with equal ShardingKey values
+// always route to the same shard. It doesn't need to match ClickHouse's own cityHash64 bit-for-bit
+// since routing only needs to be consistent within this process, not across it and the server.
+func chShardHash(value any) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(fmt.Sprint(value)))
+	return h.Sum64()
+}
+
+// ReplacePartitionOnShards loads loadSource into a staging table on every shard and atomically swaps
+// it into stagingTable's partition via ALTER TABLE ... REPLACE PARTITION, so a bulker.ReplacePartition
+// stream can target sharded writes the same way CreateStream's default (non-sharded) ReplacePartition
+// path does through newReplacePartitionStream. Unlike Insert/LoadTable's per-row sharding, a partition
+// swap has to land the same partition on every shard, so this fans the same loadSource out to all
+// shards rather than routing rows by ShardingKey.
+func (ch *ClickHouse) ReplacePartitionOnShards(ctx context.Context, targetTable *Table, stagingTable *Table, partitionID string, loadSource *LoadSource) error {
+	if len(ch.shards) == 0 {
+		return fmt.Errorf("ReplacePartitionOnShards: no shards configured")
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(ch.shards))
+	replaceQuery := fmt.Sprintf("ALTER TABLE %s REPLACE PARTITION ID '%s' FROM %s",
+		ch.fullTableName(targetTable.Name), partitionID, ch.fullTableName(stagingTable.Name))
+	for i, shard := range ch.shards {
+		wg.Add(1)
+		go func(i int, shard chShard) {
+			defer wg.Done()
+			insertStatement := fmt.Sprintf(chInsertStatement, ch.fullTableName(stagingTable.Name), strings.Join(ch.columnNamesForBatch(stagingTable), ", "))
+			file, err := os.Open(loadSource.Path)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer func() { _ = file.Close() }()
+			if err := ch.insertBatches(ctx, shard.conn, stagingTable, insertStatement, fileRowSource(file)); err != nil {
+				errs[i] = err
+				return
+			}
+			if _, err := shard.conn.Exec(ctx, replaceQuery); err != nil {
+				errs[i] = err
+			}
+		}(i, shard)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return tx.Commit()
-	//if err != nil {
-	//	return err
-	//}
-	//_, err = ch.txOrDb(ctx).ExecContext(ctx, fmt.Sprintf("OPTIMIZE TABLE %s", ch.fullTableName(targetTable.Name)))
-	//if err != nil {
-	//	return err
-	//}
-	//return nil
+// insert sends objects to ClickHouse's HTTP interface as a single gzip-compressed
+// INSERT INTO tbl FORMAT JSONEachRow body, for DSNs that only expose the HTTP protocol.
+func (ch *ClickHouse) insert(ctx context.Context, targetTable *Table, objects []types.Object) error {
+	columns := targetTable.SortedColumnNames()
+	bodyFactory := func() io.Reader {
+		pr, pw := io.Pipe()
+		gzw := gzip.NewWriter(pw)
+		go func() {
+			enc := json.NewEncoder(gzw)
+			var encErr error
+			for _, object := range objects {
+				row := make(map[string]any, len(columns))
+				for _, v := range columns {
+					converted, err := convertType(object[v], targetTable.Columns[v])
+					if err != nil {
+						encErr = err
+						break
+					}
+					row[v] = converted
+				}
+				if encErr == nil {
+					encErr = enc.Encode(row)
+				}
+				if encErr != nil {
+					break
+				}
+			}
+			_ = gzw.Close()
+			_ = pw.CloseWithError(encErr)
+		}()
+		return pr
+	}
+	return ch.insertHTTP(ctx, targetTable, bodyFactory)
+}
+
+// insertFile streams loadSource's NDJSON file straight through to ClickHouse's HTTP interface as a
+// gzip-compressed INSERT INTO tbl FORMAT JSONEachRow body, without re-decoding/re-encoding each row.
+func (ch *ClickHouse) insertFile(ctx context.Context, targetTable *Table, file *os.File) error {
+	bodyFactory := func() io.Reader {
+		// rewind: insertHTTP may call bodyFactory a second time to retry without async_insert settings
+		_, _ = file.Seek(0, io.SeekStart)
+		pr, pw := io.Pipe()
+		gzw := gzip.NewWriter(pw)
+		go func() {
+			_, err := io.Copy(gzw, file)
+			if err == nil {
+				err = gzw.Close()
+			} else {
+				_ = gzw.Close()
+			}
+			_ = pw.CloseWithError(err)
+		}()
+		return pr
+	}
+	return ch.insertHTTP(ctx, targetTable, bodyFactory)
 }
 
-func (ch *ClickHouse) CopyTables(ctx context.Context, targetTable *Table, sourceTable *Table, merge bool) error {
+// insertHTTP POSTs the body produced by bodyFactory (an already gzip-compressed JSONEachRow stream) to
+// ch.config.Dsns[0] as an INSERT INTO tbl FORMAT JSONEachRow query, setting Content-Encoding so
+// ClickHouse decompresses it server-side. bodyFactory is called again, rebuilding the body from scratch,
+// if the first attempt's async_insert settings are rejected by the server and HTTPSettings.FallbackToSync
+// is set — so it must be safe to invoke more than once.
+func (ch *ClickHouse) insertHTTP(ctx context.Context, targetTable *Table, bodyFactory func() io.Reader) (err error) {
+	query := fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", ch.fullTableName(targetTable.Name))
+	defer func() {
+		if err != nil {
+			err = errorj.ExecuteInsertError.Wrap(err, "failed to insert to table via http").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Database:    ch.config.Database,
+					Cluster:     ch.config.Cluster,
+					Table:       targetTable.Name,
+					PrimaryKeys: targetTable.GetPKFields(),
+					Statement:   query,
+				})
+		}
+	}()
+	withSettings := true
+	for {
+		values := url.Values{"query": {query}}
+		if withSettings {
+			if err := ch.addHTTPSettingsParams(ctx, values); err != nil {
+				return err
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ch.config.Dsns[0], bodyFactory())
+		if err != nil {
+			return err
+		}
+		req.URL.RawQuery = values.Encode()
+		req.Header.Set("Content-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		respBody, readErr := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+		if withSettings && ch.config.HTTPSettings != nil && ch.config.HTTPSettings.FallbackToSync &&
+			chIsAsyncInsertUnsupported(resp.StatusCode, string(respBody)) {
+			withSettings = false
+			continue
+		}
+		if readErr != nil {
+			return fmt.Errorf("clickhouse http insert failed with status %s", resp.Status)
+		}
+		return fmt.Errorf("clickhouse http insert failed with status %s: %s", resp.Status, string(respBody))
+	}
+}
+
+// chIsAsyncInsertUnsupported reports whether a ClickHouse HTTP error response indicates the server
+// rejected the async_insert settings themselves (e.g. an older version that doesn't recognize them, or
+// async_insert disabled server-side), as opposed to a genuine data/query error that retrying without
+// those settings wouldn't fix.
+func chIsAsyncInsertUnsupported(statusCode int, respBody string) bool {
+	if statusCode != http.StatusBadRequest {
+		return false
+	}
+	if !strings.Contains(respBody, "async_insert") {
+		return false
+	}
+	return strings.Contains(respBody, "Unknown setting") ||
+		strings.Contains(respBody, "disabled") ||
+		strings.Contains(respBody, "not allowed")
+}
+
+// addHTTPSettingsParams adds ch.config.HTTPSettings as query-string settings to values, the HTTP
+// insert's equivalent of withHTTPSettings' native clickhouse.WithSettings. No-op when HTTPSettings is nil.
+func (ch *ClickHouse) addHTTPSettingsParams(ctx context.Context, values url.Values) error {
+	settings, err := ch.httpSettingsMap(ctx)
+	if err != nil {
+		return err
+	}
+	for k, v := range settings {
+		values.Set(k, fmt.Sprint(v))
+	}
+	return nil
+}
+
+// withHTTPSettings attaches ch.config.HTTPSettings to ctx via clickhouse.WithSettings so the native
+// PrepareBatch/Append/Send path honors the same async_insert/deduplication settings as insertHTTP. No-op
+// when HTTPSettings is nil.
+func (ch *ClickHouse) withHTTPSettings(ctx context.Context) (context.Context, error) {
+	settings, err := ch.httpSettingsMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(settings) == 0 {
+		return ctx, nil
+	}
+	chSettings := clickhouse.Settings{}
+	for k, v := range settings {
+		chSettings[k] = v
+	}
+	return clickhouse.Context(ctx, clickhouse.WithSettings(chSettings)), nil
+}
+
+// httpSettingsMap renders ch.config.HTTPSettings into the raw setting name/value pairs shared by both
+// transports, rendering InsertDeduplicationToken's template against the calling stream's id (see
+// ctxWithBatchID). Returns nil if HTTPSettings isn't configured.
+func (ch *ClickHouse) httpSettingsMap(ctx context.Context) (map[string]any, error) {
+	hs := ch.config.HTTPSettings
+	if hs == nil {
+		return nil, nil
+	}
+	settings := map[string]any{}
+	if hs.AsyncInsert {
+		settings["async_insert"] = 1
+		if hs.WaitForAsyncInsert {
+			settings["wait_for_async_insert"] = 1
+		} else {
+			settings["wait_for_async_insert"] = 0
+		}
+	}
+	if hs.AsyncInsertMaxDataSize > 0 {
+		settings["async_insert_max_data_size"] = hs.AsyncInsertMaxDataSize
+	}
+	if hs.AsyncInsertBusyTimeoutMs > 0 {
+		settings["async_insert_busy_timeout_ms"] = hs.AsyncInsertBusyTimeoutMs
+	}
+	if hs.InsertDeduplicationToken != "" {
+		token, err := chRenderDedupToken(hs.InsertDeduplicationToken, BatchIDFromContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to render insert_deduplication_token: %w", err)
+		}
+		settings["insert_deduplication_token"] = token
+	}
+	return settings, nil
+}
+
+// chRenderDedupToken executes tokenTemplate (an InsertDeduplicationToken template) with "stream_id" and
+// "batch_id" both set to batchID, since a stream's id already doubles as its batch id (see
+// AbstractSQLStream.batchID).
+func chRenderDedupToken(tokenTemplate string, batchID string) (string, error) {
+	tmpl, err := template.New("insert_deduplication_token").Parse(tokenTemplate)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	data := map[string]string{"stream_id": batchID, "batch_id": batchID}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (ch *ClickHouse) CopyTables(ctx context.Context, targetTable *Table, sourceTable *Table, merge bool) (err error) {
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeCopyTables != nil {
+		if err = hooks.BeforeCopyTables(ctx, targetTable, sourceTable); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		runOnError(ctx, hooks, "copyTables", err)
+		if err == nil && hooks.AfterCopyTables != nil {
+			hooks.AfterCopyTables(ctx, targetTable, sourceTable)
+		}
+	}()
+	if merge && ch.mergeMode() != "" {
+		return ch.mergeCopyTables(ctx, targetTable, sourceTable)
+	}
 	return ch.copy(ctx, targetTable, sourceTable)
 }
 
+// mergeMode returns the configured EngineConfig.MergeMode if set, else the strategy implied by
+// EngineConfig.Type (replacing/collapsing/versioned_collapsing/aggregating), or "" when no engine (or
+// neither is configured), in which case CopyTables' merge flag has no effect.
+func (ch *ClickHouse) mergeMode() string {
+	engine := ch.config.Engine
+	if engine == nil {
+		return ""
+	}
+	if engine.MergeMode != "" {
+		return engine.MergeMode
+	}
+	switch engine.Type {
+	case EngineTypeReplacing:
+		return chMergeModeReplacing
+	case EngineTypeCollapsing, EngineTypeVersionedCollapsing:
+		return chMergeModeCollapsing
+	case EngineTypeAggregating:
+		return chMergeModeAggregating
+	default:
+		return ""
+	}
+}
+
+// copy performs a plain, non-merge INSERT INTO target SELECT ... FROM source.
+func (ch *ClickHouse) copy(ctx context.Context, targetTable *Table, sourceTable *Table) error {
+	columnNames := ch.columnNamesForBatch(targetTable)
+	query := fmt.Sprintf(chCopyTableTemplate, ch.fullTableName(targetTable.Name), strings.Join(columnNames, ", "), strings.Join(columnNames, ", "), ch.fullTableName(sourceTable.Name))
+	if _, err := ch.txOrDb(ctx).ExecContext(ctx, query); err != nil {
+		return errorj.CopyError.Wrap(err, "failed to copy data from source to target table").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Database:  ch.config.Database,
+				Cluster:   ch.config.Cluster,
+				Table:     targetTable.Name,
+				Statement: query,
+			})
+	}
+	return nil
+}
+
+// ImportFromQuery runs selectSQL against ch's own connection and inserts its results straight into
+// targetTable via INSERT INTO ... SELECT ... FROM (...), the ClickHouse-native pushdown path for
+// same-engine backfills and table-to-table transforms — no row ever round-trips through the Go process.
+// Wiring this up as a full cross-engine bulker.BulkMode (reading an arbitrary source *sql.DB/DSN, with
+// schema derived via tableHelper.MapTableSchema) needs types owned by the bulker package, outside this
+// adapter snapshot; this method covers the same-engine case the warehouse adapters push down directly.
+func (ch *ClickHouse) ImportFromQuery(ctx context.Context, targetTable *Table, selectSQL string) error {
+	columnNames := ch.columnNamesForBatch(targetTable)
+	query := fmt.Sprintf(chImportFromSelectTemplate, ch.fullTableName(targetTable.Name), strings.Join(columnNames, ", "), strings.Join(columnNames, ", "), selectSQL)
+	if _, err := ch.txOrDb(ctx).ExecContext(ctx, query); err != nil {
+		return errorj.CopyError.Wrap(err, "failed to import from query").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Database:  ch.config.Database,
+				Cluster:   ch.config.Cluster,
+				Table:     targetTable.Name,
+				Statement: query,
+			})
+	}
+	return nil
+}
+
+// mergeCopyTables upserts sourceTable's rows into targetTable for engines that reconcile duplicate
+// primary keys on merge: it inserts source's rows (auto-filling VersionColumn/SignColumn with
+// now64()/1 when the engine needs one sourceTable doesn't already carry), then, for
+// ReplacingMergeTree only, forces ClickHouse to fold the duplicates immediately via
+// OPTIMIZE ... FINAL DEDUPLICATE BY, scoped to only the partitions sourceTable's rows live in so the
+// optimize doesn't have to re-merge the whole table. Collapsing/VersionedCollapsing/AggregatingMergeTree
+// reconcile rows on their own background merges (collapsing via sign cancellation, aggregating via its
+// merge functions), so no explicit OPTIMIZE is issued for those. Select/Count already query targetTable
+// with chSelectFinalStatement's FINAL modifier, so they stay correct even before any merge has run.
+func (ch *ClickHouse) mergeCopyTables(ctx context.Context, targetTable *Table, sourceTable *Table) error {
+	pkFields := targetTable.GetPKFields()
+	if len(pkFields) == 0 {
+		return fmt.Errorf("mergeCopyTables: targetTable %s has no primary key to merge on", targetTable.Name)
+	}
+	engine := ch.config.Engine
+	mode := ch.mergeMode()
+	columns := targetTable.SortedColumnNames()
+	columnNames := make([]string, len(columns))
+	selectExprs := make([]string, len(columns))
+	for i, name := range columns {
+		col := ch.columnName(name)
+		columnNames[i] = col
+		switch {
+		case mode == chMergeModeReplacing && name == engine.VersionColumn:
+			selectExprs[i] = fmt.Sprintf("coalesce(%s, now64())", col)
+		case mode == chMergeModeCollapsing && name == engine.SignColumn:
+			selectExprs[i] = fmt.Sprintf("coalesce(%s, 1)", col)
+		default:
+			selectExprs[i] = col
+		}
+	}
+	insertQuery := fmt.Sprintf(chCopyTableTemplate, ch.fullTableName(targetTable.Name), strings.Join(columnNames, ", "), strings.Join(selectExprs, ", "), ch.fullTableName(sourceTable.Name))
+	if _, err := ch.txOrDb(ctx).ExecContext(ctx, insertQuery); err != nil {
+		return errorj.CopyError.Wrap(err, "failed to insert source rows for merge").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Database:    ch.config.Database,
+				Cluster:     ch.config.Cluster,
+				Table:       targetTable.Name,
+				PrimaryKeys: pkFields,
+				Statement:   insertQuery,
+			})
+	}
+	if mode != chMergeModeReplacing {
+		return nil
+	}
+	partitionIDs, err := ch.affectedPartitions(ctx, sourceTable.Name)
+	if err != nil {
+		return errorj.CopyError.Wrap(err, "failed to determine affected partitions for deduplication").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Database: ch.config.Database, Cluster: ch.config.Cluster, Table: targetTable.Name,
+			})
+	}
+	dedupBy := "(" + strings.Join(pkFields, ", ") + ")"
+	partitionClauses := []string{""}
+	if len(partitionIDs) > 0 {
+		partitionClauses = make([]string, len(partitionIDs))
+		for i, partitionID := range partitionIDs {
+			partitionClauses[i] = fmt.Sprintf("PARTITION ID '%s'", partitionID)
+		}
+	}
+	for _, partitionClause := range partitionClauses {
+		optimizeQuery := fmt.Sprintf(chOptimizeTableTemplate, ch.fullTableName(targetTable.Name), ch.getOnClusterClause(), partitionClause, dedupBy)
+		if _, err := ch.txOrDb(ctx).ExecContext(ctx, optimizeQuery); err != nil {
+			return errorj.CopyError.Wrap(err, "failed to deduplicate merged rows").
+				WithProperty(errorj.DBInfo, &types.ErrorPayload{
+					Database:    ch.config.Database,
+					Cluster:     ch.config.Cluster,
+					Table:       targetTable.Name,
+					PrimaryKeys: pkFields,
+					Statement:   optimizeQuery,
+				})
+		}
+	}
+	return nil
+}
+
+// affectedPartitions returns the distinct partition IDs (from system.parts) holding sourceTableName's
+// rows, so mergeCopyTables' OPTIMIZE ... FINAL DEDUPLICATE only re-merges the partitions the copy
+// actually touched instead of the whole table. Returns nil (meaning "optimize unscoped") for an
+// unpartitioned table.
+func (ch *ClickHouse) affectedPartitions(ctx context.Context, sourceTableName string) ([]string, error) {
+	rows, err := ch.txOrDb(ctx).QueryContext(ctx,
+		"SELECT DISTINCT partition_id FROM system.parts WHERE database = ? AND table = ? AND active",
+		ch.config.Database, sourceTableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var partitionIDs []string
+	for rows.Next() {
+		var partitionID string
+		if err := rows.Scan(&partitionID); err != nil {
+			return nil, err
+		}
+		partitionIDs = append(partitionIDs, partitionID)
+	}
+	return partitionIDs, rows.Err()
+}
+
 func (ch *ClickHouse) Delete(ctx context.Context, tableName string, deleteConditions *WhenConditions) error {
 	deleteCondition, values := ToWhenConditions(deleteConditions, ch.parameterPlaceholder, 0)
 	deleteQuery := fmt.Sprintf(chDeleteQueryTemplate, ch.fullTableName(tableName), ch.getOnClusterClause(), deleteCondition)
@@ -615,6 +1446,11 @@ func (ch *ClickHouse) dropTable(ctx context.Context, fullTableName string, ifExi
 }
 
 func (ch *ClickHouse) ReplaceTable(ctx context.Context, originalTable, replacementTable string, dropOldTable bool) (err error) {
+	snapshotName, err := ch.snapshotBeforeSwap(ctx, originalTable)
+	if err != nil {
+		return err
+	}
+
 	query := fmt.Sprintf(chExchangeTableTemplate, ch.fullTableName(originalTable), ch.fullTableName(replacementTable), ch.getOnClusterClause())
 
 	if _, err := ch.txOrDb(ctx).ExecContext(ctx, query); err != nil {
@@ -622,6 +1458,7 @@ func (ch *ClickHouse) ReplaceTable(ctx context.Context, originalTable, replaceme
 			query = fmt.Sprintf(chRenameTableTemplate, ch.fullTableName(replacementTable), ch.fullTableName(originalTable), ch.getOnClusterClause())
 
 			if _, err := ch.txOrDb(ctx).ExecContext(ctx, query); err != nil {
+				// original table never existed to begin with, so there's nothing to restore from snapshotName
 				return fmt.Errorf("error renaming [%s] table: %v", replacementTable, err)
 			}
 			if ch.config.Cluster != "" {
@@ -630,8 +1467,9 @@ func (ch *ClickHouse) ReplaceTable(ctx context.Context, originalTable, replaceme
 					return fmt.Errorf("error renaming [%s] distributed table: %v", originalTable, err)
 				}
 			}
-			return nil
+			return ch.unfreezeSnapshot(ctx, originalTable, snapshotName)
 		} else {
+			// leave snapshotName frozen: the operator can restore via ATTACH PARTITION from shadow/<name>/
 			return fmt.Errorf("error replacing [%s] table: %v", originalTable, err)
 		}
 	}
@@ -640,9 +1478,13 @@ func (ch *ClickHouse) ReplaceTable(ctx context.Context, originalTable, replaceme
 		query := fmt.Sprintf(chExchangeTableTemplate, ch.fullDistTableName(originalTable), ch.fullDistTableName(replacementTable), ch.getOnClusterClause())
 
 		if _, err := ch.txOrDb(ctx).ExecContext(ctx, query); err != nil {
+			// leave snapshotName frozen: the operator can restore via ATTACH PARTITION from shadow/<name>/
 			return fmt.Errorf("error replacing [%s] distributed table: %v", originalTable, err)
 		}
 	}
+	if err := ch.unfreezeSnapshot(ctx, originalTable, snapshotName); err != nil {
+		return err
+	}
 	if dropOldTable {
 		return ch.DropTable(ctx, replacementTable, true)
 	} else {
@@ -651,8 +1493,66 @@ func (ch *ClickHouse) ReplaceTable(ctx context.Context, originalTable, replaceme
 
 }
 
+// snapshotBeforeSwap FREEZEs originalTable under a fresh snapshot name when ClickHouseConfig.Snapshot is
+// enabled, optionally uploading the resulting shadow/<name>/ directory via the configured SnapshotSink,
+// and returns the snapshot name for unfreezeSnapshot to release on success (ReplaceTable leaves it frozen
+// on failure, so the operator can ATTACH PARTITION back from it). Returns "" when Snapshot isn't enabled.
+func (ch *ClickHouse) snapshotBeforeSwap(ctx context.Context, originalTable string) (string, error) {
+	if ch.config.Snapshot == nil || !ch.config.Snapshot.Enabled {
+		return "", nil
+	}
+	snapshotName := fmt.Sprintf("bulker_%d", time.Now().UnixNano())
+	freezeQuery := fmt.Sprintf(chFreezeTableTemplate, ch.fullTableName(originalTable), ch.getOnClusterClause(), snapshotName)
+	if _, err := ch.txOrDb(ctx).ExecContext(ctx, freezeQuery); err != nil {
+		return "", errorj.ExecuteError.Wrap(err, "failed to freeze table before replace").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Database:  ch.config.Database,
+				Cluster:   ch.config.Cluster,
+				Table:     originalTable,
+				Statement: freezeQuery,
+			})
+	}
+	if ch.config.Snapshot.Sink == "" {
+		return snapshotName, nil
+	}
+	sink, ok := snapshotSinks[ch.config.Snapshot.Sink]
+	if !ok {
+		return snapshotName, fmt.Errorf("clickhouse: unknown snapshot sink %q", ch.config.Snapshot.Sink)
+	}
+	shadowPath := fmt.Sprintf("shadow/%s/", snapshotName)
+	if err := sink.Upload(ctx, shadowPath, ch.config.Snapshot.Bucket); err != nil {
+		return snapshotName, fmt.Errorf("failed to upload snapshot %s to %s: %w", snapshotName, ch.config.Snapshot.Bucket, err)
+	}
+	return snapshotName, nil
+}
+
+// unfreezeSnapshot releases a FREEZE taken by snapshotBeforeSwap once the swap it was guarding has
+// succeeded. A no-op when snapshotName is "" (Snapshot wasn't enabled).
+func (ch *ClickHouse) unfreezeSnapshot(ctx context.Context, originalTable, snapshotName string) error {
+	if snapshotName == "" {
+		return nil
+	}
+	query := fmt.Sprintf(chUnfreezeTableTemplate, ch.fullTableName(originalTable), ch.getOnClusterClause(), snapshotName)
+	if _, err := ch.txOrDb(ctx).ExecContext(ctx, query); err != nil {
+		return errorj.ExecuteError.Wrap(err, "failed to unfreeze table after replace").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Database:  ch.config.Database,
+				Cluster:   ch.config.Cluster,
+				Table:     originalTable,
+				Statement: query,
+			})
+	}
+	return nil
+}
+
 // Close underlying sql.DB
 func (ch *ClickHouse) Close() error {
+	if ch.nativeConn != nil {
+		_ = ch.nativeConn.Close()
+	}
+	for _, s := range ch.shards {
+		_ = s.conn.Close()
+	}
 	return ch.dataSource.Close()
 }
 
@@ -684,8 +1584,12 @@ func convertType(value any, column SQLColumn) (any, error) {
 	v := types.ReformatValue(value)
 	//logging.Infof("%v (%T) was %v (%T)", v, v, value, value)
 
-	switch strings.ToLower(column.Type) {
-	case "float64":
+	lowerType := strings.ToLower(column.Type)
+	strippedType := chStripTypeWrappers(lowerType)
+	nullable := v == nil || strings.HasPrefix(lowerType, "nullable(")
+
+	switch {
+	case strippedType == "float64":
 		switch n := v.(type) {
 		case int64:
 			return float64(n), nil
@@ -694,11 +1598,11 @@ func convertType(value any, column SQLColumn) (any, error) {
 		case string:
 			f, err := strconv.ParseFloat(n, 64)
 			if err != nil {
-				return v, fmt.Errorf("error converting string to float64: %w", err)
+				return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: fmt.Errorf("error converting string to float64: %w", err)}
 			}
 			return f, nil
 		}
-	case "int64":
+	case strippedType == "int64":
 		switch n := v.(type) {
 		case int:
 			return int64(n), nil
@@ -706,25 +1610,25 @@ func convertType(value any, column SQLColumn) (any, error) {
 			if n == float64(int64(n)) {
 				return int64(n), nil
 			} else {
-				return v, fmt.Errorf("error converting float to int64: %f", n)
+				return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: fmt.Errorf("error converting float to int64: %f", n)}
 			}
 		case string:
 			f, err := strconv.Atoi(n)
 			if err != nil {
-				return v, fmt.Errorf("error converting string to int: %w", err)
+				return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: fmt.Errorf("error converting string to int: %w", err)}
 			}
 			return int64(f), nil
 		}
-	case "bool":
+	case strippedType == "bool":
 		switch n := v.(type) {
 		case string:
 			f, err := strconv.ParseBool(n)
 			if err != nil {
-				return v, fmt.Errorf("error converting string to bool: %w", err)
+				return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: fmt.Errorf("error converting string to bool: %w", err)}
 			}
 			return f, nil
 		}
-	case "uint8":
+	case strippedType == "uint8":
 		switch n := v.(type) {
 		case string:
 			f, err := strconv.ParseBool(n)
@@ -732,7 +1636,7 @@ func convertType(value any, column SQLColumn) (any, error) {
 				return f, nil
 			}
 		}
-	case "string":
+	case strippedType == "string":
 		switch n := v.(type) {
 		case time.Time:
 			return n.Format("2006-01-02 15:04:05Z"), nil
@@ -743,14 +1647,277 @@ func convertType(value any, column SQLColumn) (any, error) {
 		case bool:
 			return strconv.FormatBool(n), nil
 		}
+	case strippedType == "decimal" || strippedType == "numeric" ||
+		strings.HasPrefix(strippedType, "decimal(") || strings.HasPrefix(strippedType, "numeric("):
+		scale := 0
+		if params := chTypeParams(strippedType); len(params) == 2 {
+			if s, err := strconv.Atoi(params[1]); err == nil {
+				scale = s
+			}
+		}
+		d, err := chParseDecimal(v, scale)
+		if err != nil {
+			return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: err}
+		}
+		return d, nil
+	case strippedType == "datetime64" || strings.HasPrefix(strippedType, "datetime64("):
+		precision := 3
+		if params := chTypeParams(strippedType); len(params) > 0 {
+			if p, err := strconv.Atoi(params[0]); err == nil {
+				precision = p
+			}
+		}
+		t, err := chParseDateTime64(v, precision)
+		if err != nil {
+			return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: err}
+		}
+		return t, nil
+	case strippedType == "uuid":
+		s, ok := v.(string)
+		if !ok || !chIsValidUUID(s) {
+			return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: fmt.Errorf("%v is not a valid uuid", v)}
+		}
+		return strings.ToLower(s), nil
+	case strippedType == "ipv4" || strippedType == "ipv6":
+		s, ok := v.(string)
+		if ok {
+			if ip := net.ParseIP(s); ip != nil {
+				return ip.String(), nil
+			}
+		}
+		return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: fmt.Errorf("%v is not a valid %s address", v, strippedType)}
+	case strings.HasPrefix(strippedType, "array("):
+		params := chTypeParams(strippedType)
+		if len(params) != 1 {
+			return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: fmt.Errorf("malformed array type %q", column.Type)}
+		}
+		elems, err := chToSlice(v)
+		if err != nil {
+			return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: err}
+		}
+		converted := make([]any, len(elems))
+		elemColumn := SQLColumn{Name: column.Name, Type: params[0]}
+		for i, elem := range elems {
+			cv, err := convertType(elem, elemColumn)
+			if err != nil {
+				return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: err}
+			}
+			converted[i] = cv
+		}
+		return converted, nil
+	case strings.HasPrefix(strippedType, "map("):
+		params := chTypeParams(strippedType)
+		if len(params) != 2 {
+			return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: fmt.Errorf("malformed map type %q", column.Type)}
+		}
+		m, err := chToMap(v)
+		if err != nil {
+			return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: err}
+		}
+		converted := make(map[string]any, len(m))
+		valColumn := SQLColumn{Name: column.Name, Type: params[1]}
+		for k, val := range m {
+			cv, err := convertType(val, valColumn)
+			if err != nil {
+				return v, &TypeConversionError{Column: column.Name, Value: value, Nullable: nullable, Err: err}
+			}
+			converted[k] = cv
+		}
+		return converted, nil
 	}
 	return v, nil
 }
 
-// chColumnDDL returns column DDL (column name, mapped sql type)
-func chColumnDDL(name string, column SQLColumn, pkFields utils.Set[string], nullableFields []string) string {
+// TypeConversionError reports that convertType couldn't coerce Value into Column's ClickHouse type.
+// Nullable mirrors whether the column (or the failed value itself) accepts NULL, so a caller processing
+// a batch row-by-row can choose to null-out the offending field instead of failing the whole row.
+type TypeConversionError struct {
+	Column   string
+	Value    any
+	Nullable bool
+	Err      error
+}
+
+func (e *TypeConversionError) Error() string {
+	return fmt.Sprintf("error converting column %q value %v: %v", e.Column, e.Value, e.Err)
+}
+
+func (e *TypeConversionError) Unwrap() error {
+	return e.Err
+}
+
+// chStripTypeWrappers peels ClickHouse's Nullable(...) and LowCardinality(...) wrappers off typ
+// (already lowercased), repeatedly, since the two can nest (e.g. LowCardinality(Nullable(String))).
+// Conversion behaves identically regardless of either wrapper: Nullable only affects whether NULL is a
+// valid value, LowCardinality only affects on-disk storage.
+func chStripTypeWrappers(typ string) string {
+	for {
+		switch {
+		case strings.HasPrefix(typ, "nullable(") && strings.HasSuffix(typ, ")"):
+			typ = typ[len("nullable(") : len(typ)-1]
+		case strings.HasPrefix(typ, "lowcardinality(") && strings.HasSuffix(typ, ")"):
+			typ = typ[len("lowcardinality(") : len(typ)-1]
+		default:
+			return typ
+		}
+	}
+}
+
+// chTypeParams splits the comma-separated parameter list inside typ's outermost parentheses, e.g.
+// "decimal(10, 2)" -> ["10", "2"], respecting nested parentheses so "map(string, array(int64))"
+// splits into ["string", "array(int64)"] rather than three pieces. Returns nil if typ has no
+// parenthesized parameters.
+func chTypeParams(typ string) []string {
+	open := strings.Index(typ, "(")
+	if open < 0 || !strings.HasSuffix(typ, ")") {
+		return nil
+	}
+	inner := typ[open+1 : len(typ)-1]
+	var params []string
+	depth := 0
+	start := 0
+	for i, r := range inner {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				params = append(params, strings.TrimSpace(inner[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	params = append(params, strings.TrimSpace(inner[start:]))
+	return params
+}
+
+// chParseDecimal coerces v into a decimal.Decimal rounded to scale, for a Decimal(P,S)/Numeric(P,S)
+// column. Strings and floats are both accepted since upstream sources commonly serialize decimals as
+// either, but strings are preferred wherever precision matters since they avoid a float round-trip.
+func chParseDecimal(v any, scale int) (decimal.Decimal, error) {
+	switch n := v.(type) {
+	case decimal.Decimal:
+		return n.Round(int32(scale)), nil
+	case string:
+		d, err := decimal.NewFromString(n)
+		if err != nil {
+			return decimal.Decimal{}, fmt.Errorf("error converting string to decimal: %w", err)
+		}
+		return d.Round(int32(scale)), nil
+	case float64:
+		return decimal.NewFromFloat(n).Round(int32(scale)), nil
+	case int64:
+		return decimal.NewFromInt(n).Round(int32(scale)), nil
+	case int:
+		return decimal.NewFromInt(int64(n)).Round(int32(scale)), nil
+	}
+	return decimal.Decimal{}, fmt.Errorf("error converting %T to decimal", v)
+}
+
+// chParseDateTime64 coerces v into a time.Time for a DateTime64(precision[, tz]) column: time.Time
+// passes through unchanged, RFC3339(-Nano) strings are parsed directly, and numeric strings/int64/
+// float64 are treated as a Unix epoch value scaled by precision (0=seconds, 3=milliseconds,
+// 6=microseconds, 9=nanoseconds) — DateTime64's own tick unit.
+func chParseDateTime64(v any, precision int) (time.Time, error) {
+	switch n := v.(type) {
+	case time.Time:
+		return n, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, n); err == nil {
+			return t, nil
+		}
+		epoch, err := strconv.ParseInt(n, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error converting string to datetime64: %w", err)
+		}
+		return chEpochToTime(epoch, precision), nil
+	case int64:
+		return chEpochToTime(n, precision), nil
+	case float64:
+		return chEpochToTime(int64(n), precision), nil
+	}
+	return time.Time{}, fmt.Errorf("error converting %T to datetime64", v)
+}
+
+// chEpochToTime expands an integer epoch value at the given DateTime64 precision (0=seconds,
+// 3=milliseconds, 6=microseconds, 9=nanoseconds, anything higher treated as nanoseconds) into a time.Time.
+func chEpochToTime(epoch int64, precision int) time.Time {
+	switch {
+	case precision >= 9:
+		return time.Unix(0, epoch)
+	case precision >= 6:
+		return time.Unix(0, epoch*int64(time.Microsecond))
+	case precision >= 3:
+		return time.Unix(0, epoch*int64(time.Millisecond))
+	default:
+		return time.Unix(epoch, 0)
+	}
+}
+
+// chIsValidUUID reports whether s is a canonical 8-4-4-4-12 hex UUID. A hand-rolled format check avoids
+// pulling in a UUID library just to validate a string that's otherwise passed straight through to
+// ClickHouse as-is.
+func chIsValidUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if r != '-' {
+				return false
+			}
+			continue
+		}
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// chToSlice coerces v into a []any for an Array(T) column: a decoded []any passes through, and a JSON
+// array string (as produced by sources that serialize arrays as text) is unmarshalled.
+func chToSlice(v any) ([]any, error) {
+	switch n := v.(type) {
+	case []any:
+		return n, nil
+	case string:
+		var out []any
+		if err := json.Unmarshal([]byte(n), &out); err != nil {
+			return nil, fmt.Errorf("error parsing array json: %w", err)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("error converting %T to array", v)
+}
+
+// chToMap coerces v into a map[string]any for a Map(K,V) column: a decoded map[string]any passes
+// through, and a JSON object string is unmarshalled. ClickHouse's Map key type is restricted to
+// strings/integers/etc., but JSON objects are always string-keyed, so this covers the common case.
+func chToMap(v any) (map[string]any, error) {
+	switch n := v.(type) {
+	case map[string]any:
+		return n, nil
+	case string:
+		var out map[string]any
+		if err := json.Unmarshal([]byte(n), &out); err != nil {
+			return nil, fmt.Errorf("error parsing map json: %w", err)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("error converting %T to map", v)
+}
+
+// chColumnDDL returns column DDL (column name, mapped sql type, optional LowCardinality wrapping and
+// CODEC(...) clause from columnOptions/defaultCodec)
+func chColumnDDL(name string, column SQLColumn, pkFields utils.Set[string], nullableFields []string, columnOptions ColumnEngineOptions, defaultCodec string) string {
 	//get sql type
 	columnSQLType := column.GetDDLType()
+	if columnOptions.LowCardinality {
+		columnSQLType = fmt.Sprintf("LowCardinality(%s)", columnSQLType)
+	}
 
 	//get nullable or plain
 	var columnTypeDDL string
@@ -760,7 +1927,21 @@ func chColumnDDL(name string, column SQLColumn, pkFields utils.Set[string], null
 		columnTypeDDL = columnSQLType
 	}
 
-	return fmt.Sprintf(`"%s" %s`, name, columnTypeDDL)
+	codec := columnOptions.Codec
+	if codec == "" {
+		codec = defaultCodec
+	}
+	var codecClause string
+	if codec != "" {
+		codecClause = fmt.Sprintf(" CODEC(%s)", codec)
+	}
+
+	var ttlClause string
+	if column.TTLExpression != "" {
+		ttlClause = fmt.Sprintf(" TTL %s", column.TTLExpression)
+	}
+
+	return fmt.Sprintf(`"%s" %s%s%s`, name, columnTypeDDL, codecClause, ttlClause)
 }
 
 // chTypecastFunc returns "?" placeholder or with typecast
@@ -856,8 +2037,98 @@ type TableStatementFactory struct {
 	partitionClause  string
 	orderByClause    string
 	primaryKeyClause string
+	sampleByClause   string
+	ttlClause        string
+	settingsClause   string
 
 	engineStatementFormat bool
+
+	// replicated/replicationPath/replicaName are the cluster-replication context CreateTableAsSelectStatement
+	// needs to build a Replicated* engine for an engineOverrides of its own, matching the cluster this
+	// factory's own engineStatement was built against. replicationPath/replicaName are already rendered
+	// (chRenderReplicationTemplate) and, like engineStatement, may still contain a "%[1]s" table name verb.
+	replicated      bool
+	replicationPath string
+	replicaName     string
+}
+
+// chRenderReplicationTemplate substitutes "{database}" with database and "{table}" with the Sprintf
+// verb CreateTableStatement later fills in with the actual table name. "{shard}", "{replica}", "{layer}"
+// and "{uuid}" are left untouched for ClickHouse's own macro substitution.
+func chRenderReplicationTemplate(tmpl string, database string) string {
+	tmpl = strings.ReplaceAll(tmpl, "{database}", database)
+	return strings.ReplaceAll(tmpl, "{table}", "%[1]s")
+}
+
+// chEngineExpression composes the ENGINE clause body (everything after "ENGINE = ") for
+// engine.Type (EngineTypeReplacing when engine is nil or engine.Type is empty, preserving this
+// adapter's pre-EngineType default). When replicated, the engine name is prefixed "Replicated" and
+// replicationPath/replicaName are its first two constructor arguments, ahead of any engine-specific
+// parameter (VersionColumn, SignColumn, SummingColumns).
+func chEngineExpression(engine *EngineConfig, replicated bool, replicationPath, replicaName string) string {
+	engineType := EngineTypeReplacing
+	var versionColumn, signColumn string
+	var summingColumns []string
+	if engine != nil {
+		if engine.Type != "" {
+			engineType = engine.Type
+		}
+		versionColumn = engine.VersionColumn
+		signColumn = engine.SignColumn
+		summingColumns = engine.SummingColumns
+	}
+
+	var name string
+	var params []string
+	switch engineType {
+	case EngineTypeMergeTree:
+		name = "MergeTree"
+	case EngineTypeSumming:
+		name = "SummingMergeTree"
+		if len(summingColumns) > 0 {
+			params = []string{"(" + strings.Join(summingColumns, ", ") + ")"}
+		}
+	case EngineTypeAggregating:
+		name = "AggregatingMergeTree"
+	case EngineTypeCollapsing:
+		name = "CollapsingMergeTree"
+		params = []string{signColumn}
+	case EngineTypeVersionedCollapsing:
+		name = "VersionedCollapsingMergeTree"
+		params = []string{signColumn, versionColumn}
+	default: // EngineTypeReplacing
+		name = "ReplacingMergeTree"
+		if versionColumn != "" {
+			params = []string{versionColumn}
+		}
+	}
+
+	if replicated {
+		name = "Replicated" + name
+		params = append([]string{"'" + replicationPath + "'", "'" + replicaName + "'"}, params...)
+	}
+	return name + "(" + strings.Join(params, ", ") + ")"
+}
+
+// chValidateEngineConfig rejects an EngineConfig whose Type requires columns chEngineExpression has no
+// sane default for: CollapsingMergeTree's Sign(column) and VersionedCollapsingMergeTree's
+// Sign(column, version) both need the caller to say which columns those are, since guessing a column
+// name (e.g. "sign") would silently corrupt tables that don't have one.
+func chValidateEngineConfig(engine *EngineConfig) error {
+	if engine == nil {
+		return nil
+	}
+	switch engine.Type {
+	case EngineTypeCollapsing:
+		if engine.SignColumn == "" {
+			return errors.New("clickhouse: engine.type collapsing requires engine.sign_column")
+		}
+	case EngineTypeVersionedCollapsing:
+		if engine.SignColumn == "" || engine.VersionColumn == "" {
+			return errors.New("clickhouse: engine.type versioned_collapsing requires engine.sign_column and engine.version_column")
+		}
+	}
+	return nil
 }
 
 func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory, error) {
@@ -872,6 +2143,7 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 	partitionClause := chDefaultPartition
 	orderByClause := chDefaultOrderBy
 	primaryKeyClause := chDefaultPrimaryKey
+	var sampleByClause, ttlClause, settingsClause string
 	if config.Engine != nil {
 		//raw statement overrides all provided config parameters
 		if config.Engine.RawStatement != "" {
@@ -891,17 +2163,46 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 		if len(config.Engine.PrimaryKeys) > 0 {
 			primaryKeyClause = "PRIMARY KEY (" + strings.Join(config.Engine.PrimaryKeys, ", ") + ")"
 		}
+		if config.Engine.SampleBy != "" {
+			sampleByClause = "SAMPLE BY " + config.Engine.SampleBy
+		}
+		if config.Engine.TableTTL != "" {
+			ttlClause = "TTL " + config.Engine.TableTTL
+		}
+		if len(config.Engine.Settings) > 0 {
+			settingsClause = "SETTINGS " + chFormatSettings(config.Engine.Settings)
+		}
+		if err := chValidateEngineConfig(config.Engine); err != nil {
+			return nil, err
+		}
 	}
 
 	var engineStatement string
 	var engineStatementFormat bool
+	var replicated bool
+	var replicationPath, replicaName string
 	if config.Cluster != "" {
-		//create engine statement with ReplicatedReplacingMergeTree() engine. We need to replace %s with tableName on creating statement
-		engineStatement = `ENGINE = ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/` + config.Database + `/%s', '{replica}')`
-		engineStatementFormat = true
+		replicated = true
+		replicationPath = chDefaultReplicationPath
+		replicaName = chDefaultReplicaName
+		if config.Engine != nil {
+			if config.Engine.ReplicationPath != "" {
+				replicationPath = config.Engine.ReplicationPath
+			}
+			if config.Engine.ReplicaName != "" {
+				replicaName = config.Engine.ReplicaName
+			}
+		}
+		if !strings.Contains(replicationPath, "{table}") && !strings.Contains(replicationPath, "{uuid}") {
+			return nil, fmt.Errorf("clickhouse: engine.replication_path %q must reference {table} or {uuid} so distinct tables don't collide on the same znode", replicationPath)
+		}
+		replicationPath = chRenderReplicationTemplate(replicationPath, config.Database)
+		replicaName = chRenderReplicationTemplate(replicaName, config.Database)
+		//create engine statement with the configured Replicated* engine. We need to replace %[1]s with tableName on creating statement
+		engineStatement = "ENGINE = " + chEngineExpression(config.Engine, true, replicationPath, replicaName)
+		engineStatementFormat = strings.Contains(engineStatement, "%[1]s")
 	} else {
-		//create table template with ReplacingMergeTree() engine
-		engineStatement = `ENGINE = ReplacingMergeTree()`
+		engineStatement = "ENGINE = " + chEngineExpression(config.Engine, false, "", "")
 	}
 
 	return &TableStatementFactory{
@@ -911,6 +2212,12 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 		partitionClause:       partitionClause,
 		orderByClause:         orderByClause,
 		primaryKeyClause:      primaryKeyClause,
+		replicated:            replicated,
+		replicationPath:       replicationPath,
+		replicaName:           replicaName,
+		sampleByClause:        sampleByClause,
+		ttlClause:             ttlClause,
+		settingsClause:        settingsClause,
 		engineStatementFormat: engineStatementFormat,
 	}, nil
 }
@@ -922,5 +2229,62 @@ func (tsf TableStatementFactory) CreateTableStatement(tableName, columnsClause s
 		engineStatement = fmt.Sprintf(engineStatement, tableName)
 	}
 	return fmt.Sprintf(chCreateTableTemplate, tableName, tsf.onClusterClause, columnsClause, engineStatement,
-		tsf.partitionClause, tsf.orderByClause, tsf.primaryKeyClause)
+		tsf.partitionClause, tsf.orderByClause, tsf.primaryKeyClause, tsf.sampleByClause, tsf.ttlClause, tsf.settingsClause)
+}
+
+// CreateTableAsSelectStatement returns a CREATE TABLE ... AS <selectSQL> statement that materializes
+// selectSQL server-side instead of declaring a column list, for "sql model" sources that transform data
+// in-warehouse rather than round-tripping rows through the loader. engineOverrides lets the model pick
+// its own PartitionFields/OrderFields/PrimaryKeys/Type/etc.; any left zero fall back to tsf's own table
+// defaults, and engineOverrides is validated the same way config.Engine is in NewTableStatementFactory.
+// The caller is responsible for creating the matching Distributed wrapper afterward (see
+// ClickHouse.createDistributedTableInTransaction) when a cluster is configured — this only materializes
+// the underlying per-shard table.
+func (tsf TableStatementFactory) CreateTableAsSelectStatement(tableName, selectSQL string, engineOverrides *EngineConfig) (string, error) {
+	if err := chValidateEngineConfig(engineOverrides); err != nil {
+		return "", err
+	}
+
+	partitionClause := tsf.partitionClause
+	orderByClause := tsf.orderByClause
+	primaryKeyClause := tsf.primaryKeyClause
+	if engineOverrides != nil {
+		if len(engineOverrides.PartitionFields) > 0 {
+			partitionClause = "PARTITION BY (" + extractStatement(engineOverrides.PartitionFields) + ")"
+		}
+		if len(engineOverrides.OrderFields) > 0 {
+			orderByClause = "ORDER BY (" + extractStatement(engineOverrides.OrderFields) + ")"
+		}
+		if len(engineOverrides.PrimaryKeys) > 0 {
+			primaryKeyClause = "PRIMARY KEY (" + strings.Join(engineOverrides.PrimaryKeys, ", ") + ")"
+		}
+	}
+
+	engineStatement := tsf.engineStatement
+	engineStatementFormat := tsf.engineStatementFormat
+	if engineOverrides != nil {
+		engineStatement = "ENGINE = " + chEngineExpression(engineOverrides, tsf.replicated, tsf.replicationPath, tsf.replicaName)
+		engineStatementFormat = strings.Contains(engineStatement, "%[1]s")
+	}
+	if engineStatementFormat {
+		engineStatement = fmt.Sprintf(engineStatement, tableName)
+	}
+
+	return fmt.Sprintf(chCreateTableAsSelectTemplate, tableName, tsf.onClusterClause, engineStatement,
+		partitionClause, orderByClause, primaryKeyClause, selectSQL), nil
+}
+
+// chFormatSettings renders a SETTINGS clause body ("k1=v1, k2=v2") from settings, sorted by key for a
+// deterministic DDL string (useful for tests and for diffing CREATE TABLE statements across runs).
+func chFormatSettings(settings map[string]string) string {
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%s", k, settings[k])
+	}
+	return strings.Join(parts, ", ")
 }
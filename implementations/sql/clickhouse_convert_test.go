@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestConvertType(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      any
+		columnType string
+		expected   any
+		wantErr    bool
+	}{
+		{name: "float64_from_string", value: "1.5", columnType: "float64", expected: 1.5},
+		{name: "int64_from_string", value: "42", columnType: "int64", expected: int64(42)},
+		{name: "bool_from_string", value: "true", columnType: "bool", expected: true},
+		{name: "decimal_from_string", value: "19.9999", columnType: "decimal(10, 2)", expected: decimal.NewFromFloat(20.00)},
+		{name: "decimal_from_float", value: 1.5, columnType: "numeric(10, 2)", expected: decimal.NewFromFloat(1.5)},
+		{name: "decimal_malformed_string", value: "not-a-number", columnType: "decimal(10, 2)", wantErr: true},
+		{name: "datetime64_from_rfc3339", value: "2022-08-18T14:17:22.123Z", columnType: "datetime64(3, 'UTC')", expected: time.Date(2022, 8, 18, 14, 17, 22, 123000000, time.UTC)},
+		{name: "datetime64_from_epoch_millis", value: int64(1660832242123), columnType: "datetime64(3)", expected: time.UnixMilli(1660832242123)},
+		{name: "lowcardinality_string_unwraps", value: int64(1), columnType: "lowcardinality(string)", expected: "1"},
+		{name: "nullable_float64_unwraps", value: "2.5", columnType: "nullable(float64)", expected: 2.5},
+		{name: "uuid_valid", value: "123e4567-e89b-12d3-a456-426614174000", columnType: "uuid", expected: "123e4567-e89b-12d3-a456-426614174000"},
+		{name: "uuid_invalid", value: "not-a-uuid", columnType: "uuid", wantErr: true},
+		{name: "ipv4_valid", value: "192.168.1.1", columnType: "ipv4", expected: "192.168.1.1"},
+		{name: "ipv6_valid", value: "::1", columnType: "ipv6", expected: "::1"},
+		{name: "ipv4_invalid", value: "not-an-ip", columnType: "ipv4", wantErr: true},
+		{name: "array_of_strings_from_json", value: `["a", "b"]`, columnType: "array(string)", expected: []any{"a", "b"}},
+		{name: "array_of_int64_from_slice", value: []any{float64(1), float64(2)}, columnType: "array(int64)", expected: []any{int64(1), int64(2)}},
+		{name: "map_from_json", value: `{"a": "1"}`, columnType: "map(string, string)", expected: map[string]any{"a": "1"}},
+		{name: "map_malformed_json", value: `not-json`, columnType: "map(string, string)", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := convertType(tt.value, SQLColumn{Name: tt.name, Type: tt.columnType})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got converted value %v", converted)
+				}
+				var convErr *TypeConversionError
+				if !errors.As(err, &convErr) {
+					t.Fatalf("expected a *TypeConversionError, got %T: %v", err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d, ok := tt.expected.(decimal.Decimal); ok {
+				got, ok := converted.(decimal.Decimal)
+				if !ok || !got.Equal(d) {
+					t.Fatalf("expected decimal %v, got %v", d, converted)
+				}
+				return
+			}
+			if !equalConverted(converted, tt.expected) {
+				t.Fatalf("expected %#v, got %#v", tt.expected, converted)
+			}
+		})
+	}
+}
+
+// equalConverted compares convertType's result against the expected value for the plain scalar/slice/map
+// cases this test matrix exercises; it isn't meant as a general-purpose deep-equal replacement.
+func equalConverted(got, want any) bool {
+	switch w := want.(type) {
+	case time.Time:
+		g, ok := got.(time.Time)
+		return ok && g.Equal(w)
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !equalConverted(g[i], w[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for k, v := range w {
+			if !equalConverted(g[k], v) {
+				return false
+			}
+		}
+		return true
+	default:
+		return got == want
+	}
+}
@@ -0,0 +1,212 @@
+package sql
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/jitsucom/bulker/base/logging"
+	"github.com/jitsucom/bulker/bulker"
+	"github.com/jitsucom/bulker/types"
+)
+
+// DuplicateRow is one row FlushDuplicates writes to a WithDuplicateDetection sidecar table: the original
+// object plus enough metadata to trace which batch and which row within it produced the PK collision.
+type DuplicateRow struct {
+	Object   types.Object
+	PKHash   string
+	BatchID  string
+	RowIndex int64
+}
+
+// dupDetectionConfig is WithDuplicateDetection's resolved state: the sidecar table duplicate rows are
+// written to on FlushDuplicates.
+type dupDetectionConfig struct {
+	sidecarTable string
+}
+
+var dupDetectionOption = bulker.Option[*dupDetectionConfig]{Key: "dupDetection"}
+
+// WithDuplicateDetection makes a merge stream (WithPrimaryKey + MergeRows) hash each event's primary-key
+// columns and compare it against every PK hash already seen in the batch so far (preprocess.detectDuplicate).
+// Instead of silently letting the adapter's MERGE/ON CONFLICT pick a winner, every row whose PK hash
+// collides with an earlier one in the same batch is buffered and, on FlushDuplicates, written to
+// sidecarTable so it can be audited after the fact.
+//
+// The in-batch set of seen hashes is dupSeenSet (see AbstractSQLStream.dupSeen): it holds hashes in memory
+// up to dupSeenSpillThreshold and spills the rest to a temp file, since this snapshot has no go.mod to add
+// a real pebble/boltdb dependency to. See dupSeenSet's doc comment for the tradeoff that trades away.
+func WithDuplicateDetection(sidecarTable string) bulker.StreamOption {
+	return func(options *bulker.StreamOptions) {
+		dupDetectionOption.Set(options, &dupDetectionConfig{sidecarTable: sidecarTable})
+	}
+}
+
+// pkHash hashes object's primary-key column values (in a stable, sorted-by-name order) so two events
+// with the same PK produce the same hash regardless of what order their other fields were set in.
+func pkHash(pkFields map[string]struct{}, object types.Object) string {
+	names := make([]string, 0, len(pkFields))
+	for name := range pkFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s=%v\x1f", name, object[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dupSeenSpillThreshold caps how many PK hashes a dupSeenSet keeps in memory before spilling the rest to
+// disk, so a batch with far more distinct keys than comfortably fit in memory doesn't grow ps.dupSeen
+// without bound.
+const dupSeenSpillThreshold = 200_000
+
+// dupSeenSet is detectDuplicate's "have I seen this PK hash in this batch already" set. Hashes are held in
+// a map up to dupSeenSpillThreshold; beyond that, new hashes spill to an append-only temp file created
+// lazily on first spill, and membership checks fall back to scanning that file. This snapshot has no
+// go.mod to vendor a real embedded KV store (pebble/boltdb) in, so the overflow store here is a flat file
+// rather than an indexed one — lookups against it are O(n) instead of O(log n) — but it genuinely bounds
+// memory for an oversized batch instead of just documenting that it doesn't. Close removes the temp file;
+// FlushDuplicates calls it from a defer so it runs regardless of whether the sidecar write itself succeeds,
+// the same way lightning's engineManager.close always tears down its on-disk engines.
+type dupSeenSet struct {
+	mem          map[string]struct{}
+	overflow     *os.File
+	overflowPath string
+}
+
+func newDupSeenSet() *dupSeenSet {
+	return &dupSeenSet{mem: make(map[string]struct{})}
+}
+
+// Add reports whether hash was already recorded by an earlier call, and records it if not.
+func (s *dupSeenSet) Add(hash string) (bool, error) {
+	if _, ok := s.mem[hash]; ok {
+		return true, nil
+	}
+	if s.overflow != nil {
+		seen, err := s.scanOverflow(hash)
+		if err != nil {
+			return false, err
+		}
+		if seen {
+			return true, nil
+		}
+	}
+	if len(s.mem) < dupSeenSpillThreshold {
+		s.mem[hash] = struct{}{}
+		return false, nil
+	}
+	return false, s.spill(hash)
+}
+
+// spill appends hash to the overflow temp file, creating it on first use.
+func (s *dupSeenSet) spill(hash string) error {
+	if s.overflow == nil {
+		f, err := os.CreateTemp("", "bulker-dupseen-*")
+		if err != nil {
+			return err
+		}
+		s.overflow = f
+		s.overflowPath = f.Name()
+	}
+	_, err := s.overflow.WriteString(hash + "\n")
+	return err
+}
+
+// scanOverflow reports whether hash is among the lines already spilled to the overflow file.
+func (s *dupSeenSet) scanOverflow(hash string) (bool, error) {
+	if _, err := s.overflow.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	scanner := bufio.NewScanner(s.overflow)
+	for scanner.Scan() {
+		if scanner.Text() == hash {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// Close removes the overflow temp file, if Add ever created one. Safe to call on a set that never spilled.
+func (s *dupSeenSet) Close() error {
+	if s.overflow == nil {
+		return nil
+	}
+	path := s.overflowPath
+	_ = s.overflow.Close()
+	s.overflow = nil
+	return os.Remove(path)
+}
+
+// detectDuplicate records object as a DuplicateRow if its primary-key hash was already seen earlier in
+// this stream's batch, otherwise marks the hash as seen. No-ops unless the stream is a merge stream with
+// WithDuplicateDetection enabled.
+func (ps *AbstractSQLStream) detectDuplicate(object types.Object) {
+	if ps.dupDetection == nil || !ps.merge {
+		return
+	}
+	pkFields := primaryKeyOption.Get(&ps.options)
+	if len(pkFields) == 0 {
+		return
+	}
+	hash := pkHash(pkFields, object)
+	if ps.dupSeen == nil {
+		ps.dupSeen = newDupSeenSet()
+	}
+	seen, err := ps.dupSeen.Add(hash)
+	if err != nil {
+		// Recording/spilling the hash failed (e.g. disk full). This is an audit-only concern, so it's
+		// logged and the event still ingests normally rather than failing the stream over it.
+		logging.Warnf("duplicate detection: failed to record PK hash for table %s: %v", ps.tableName, err)
+		return
+	}
+	if seen {
+		ps.duplicates = append(ps.duplicates, DuplicateRow{
+			Object:   object,
+			PKHash:   hash,
+			BatchID:  ps.batchID,
+			RowIndex: int64(ps.state.ProcessedRows),
+		})
+	}
+}
+
+// FlushDuplicates writes every DuplicateRow detectDuplicate buffered during this stream to the
+// WithDuplicateDetection sidecar table, then clears the in-memory set and buffer regardless of whether
+// the write succeeds, so a stream's duplicate-detection state never outlives it. The concrete stream
+// types that call Complete() (not part of this adapter snapshot) are expected to call this from their
+// own Complete() before returning, the same way they'd call postComplete.
+func (ps *AbstractSQLStream) FlushDuplicates(ctx context.Context) error {
+	if ps.dupDetection == nil {
+		return nil
+	}
+	defer func() {
+		if ps.dupSeen != nil {
+			if closeErr := ps.dupSeen.Close(); closeErr != nil {
+				logging.Warnf("duplicate detection: failed to remove overflow spill file for table %s: %v", ps.tableName, closeErr)
+			}
+		}
+		ps.dupSeen = nil
+		ps.duplicates = nil
+	}()
+	if len(ps.duplicates) == 0 {
+		return nil
+	}
+	sidecarTable := &Table{Name: ps.dupDetection.sidecarTable}
+	objects := make([]types.Object, len(ps.duplicates))
+	for i, dup := range ps.duplicates {
+		objects[i] = types.Object{
+			"original_object": fmt.Sprintf("%v", dup.Object),
+			"pk_hash":         dup.PKHash,
+			"batch_id":        dup.BatchID,
+			"row_index":       dup.RowIndex,
+		}
+	}
+	return ps.sqlAdapter.Insert(ctx, sidecarTable, false, objects)
+}
@@ -0,0 +1,86 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jitsucom/bulker/bulker"
+	"github.com/jitsucom/bulker/types"
+)
+
+// stubInsertAdapter satisfies SQLAdapter by embedding a nil one and overriding only Insert, so
+// FlushDuplicates' call can be captured without needing every other SQLAdapter method.
+type stubInsertAdapter struct {
+	SQLAdapter
+	insertedTable *Table
+	inserted      []types.Object
+}
+
+func (s *stubInsertAdapter) Insert(_ context.Context, table *Table, _ bool, objects []types.Object) error {
+	s.insertedTable = table
+	s.inserted = append(s.inserted, objects...)
+	return nil
+}
+
+func TestFlushDuplicatesWritesSidecarRowsAndResets(t *testing.T) {
+	adapter := &stubInsertAdapter{}
+	ps := AbstractSQLStream{
+		sqlAdapter:   adapter,
+		merge:        true,
+		batchID:      "batch-1",
+		dupDetection: &dupDetectionConfig{sidecarTable: "dup_events"},
+	}
+	primaryKeyOption.Set(&ps.options, map[string]struct{}{"id": {}})
+
+	ps.detectDuplicate(types.Object{"id": 1, "v": "a"})
+	ps.detectDuplicate(types.Object{"id": 1, "v": "b"}) // same PK -> duplicate
+	ps.detectDuplicate(types.Object{"id": 2, "v": "c"})
+
+	if len(ps.duplicates) != 1 {
+		t.Fatalf("expected 1 buffered duplicate before flush, got %d", len(ps.duplicates))
+	}
+
+	if err := ps.FlushDuplicates(context.Background()); err != nil {
+		t.Fatalf("FlushDuplicates: %v", err)
+	}
+
+	if adapter.insertedTable == nil || adapter.insertedTable.Name != "dup_events" {
+		t.Fatalf("expected a row written to sidecar table dup_events, got %+v", adapter.insertedTable)
+	}
+	if len(adapter.inserted) != 1 {
+		t.Fatalf("expected exactly 1 row inserted into sidecar table, got %d", len(adapter.inserted))
+	}
+	if adapter.inserted[0]["batch_id"] != "batch-1" {
+		t.Fatalf("expected inserted row to carry batch_id, got %+v", adapter.inserted[0])
+	}
+
+	if ps.dupSeen != nil || ps.duplicates != nil {
+		t.Fatalf("expected FlushDuplicates to reset dupSeen/duplicates, got dupSeen=%v duplicates=%v", ps.dupSeen, ps.duplicates)
+	}
+}
+
+func TestPostCompleteFlushesDuplicates(t *testing.T) {
+	adapter := &stubInsertAdapter{}
+	ps := AbstractSQLStream{
+		sqlAdapter:   adapter,
+		merge:        true,
+		batchID:      "batch-2",
+		dupDetection: &dupDetectionConfig{sidecarTable: "dup_events"},
+		state:        bulker.State{Status: bulker.Active},
+	}
+	primaryKeyOption.Set(&ps.options, map[string]struct{}{"id": {}})
+
+	ps.detectDuplicate(types.Object{"id": 1})
+	ps.detectDuplicate(types.Object{"id": 1})
+
+	if _, err := ps.postComplete(nil); err != nil {
+		t.Fatalf("postComplete: %v", err)
+	}
+
+	if len(adapter.inserted) != 1 {
+		t.Fatalf("expected postComplete to flush 1 buffered duplicate, got %d", len(adapter.inserted))
+	}
+	if ps.duplicates != nil {
+		t.Fatalf("expected postComplete to clear duplicates buffer, got %v", ps.duplicates)
+	}
+}
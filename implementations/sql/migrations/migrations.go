@@ -0,0 +1,109 @@
+// Package migrations lets operators declare versioned, ordered migrations per destination table
+// (rename column, backfill, change primary key, add index, ...) that are applied idempotently
+// before any stream opens the table. Ergonomics are modeled after goose/mattes-migrate.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/jitsucom/bulker/base/errorj"
+)
+
+// Executor is the minimal subset of a SQL adapter's transaction/connection needed to run migrations.
+// It is satisfied by both a raw *sql.DB/*sql.Tx and the TxOrDB wrapper used elsewhere in this package.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Migration is a single versioned change to a destination table.
+type Migration struct {
+	Version int64
+	Table   string
+	Up      func(ctx context.Context, exec Executor) error
+	Down    func(ctx context.Context, exec Executor) error
+}
+
+// checksum returns a short content hash of the migration's identity, stored alongside applied_at so
+// operators can detect a migration that was edited after being applied.
+func (m Migration) checksum() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", m.Version, m.Table)))
+	return hex.EncodeToString(sum[:8])
+}
+
+const (
+	// CreateMigrationsTableTemplate creates the tracking table; %s is the quoted schema/dataset-qualified table name.
+	CreateMigrationsTableTemplate = `CREATE TABLE IF NOT EXISTS %s (
+	version bigint PRIMARY KEY,
+	table_name text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now(),
+	checksum text NOT NULL
+)`
+	// AdvisoryLockTemplate acquires a session-level Postgres advisory lock keyed by table name.
+	AdvisoryLockTemplate = `SELECT pg_advisory_lock(hashtext($1))`
+	// AdvisoryUnlockTemplate releases the lock acquired via AdvisoryLockTemplate.
+	AdvisoryUnlockTemplate = `SELECT pg_advisory_unlock(hashtext($1))`
+	// MaxAppliedVersionTemplate returns the highest applied migration version for a table, or null.
+	MaxAppliedVersionTemplate = `SELECT max(version) FROM %s WHERE table_name = $1`
+	// InsertAppliedTemplate records a migration as applied.
+	InsertAppliedTemplate = `INSERT INTO %s (version, table_name, checksum) VALUES ($1, $2, $3)`
+
+	// DefaultMigrationsTable is the tracking table name created by InitDatabase.
+	DefaultMigrationsTable = "bulker_schema_migrations"
+)
+
+// Runner applies a fixed set of Migrations, in version order, to the table(s) they target.
+type Runner struct {
+	migrationsTable string
+	migrations      []Migration
+}
+
+// NewRunner returns a Runner that tracks applied migrations in the given tracking table
+// (DefaultMigrationsTable if empty).
+func NewRunner(migrationsTable string, migrations ...Migration) *Runner {
+	if migrationsTable == "" {
+		migrationsTable = DefaultMigrationsTable
+	}
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Runner{migrationsTable: migrationsTable, migrations: sorted}
+}
+
+// Apply acquires a Postgres advisory lock keyed by tableName, reads the highest applied version for
+// that table, and runs every pending migration against exec in order, recording each one as it succeeds.
+// Callers are expected to run Apply inside a transaction so a failing migration rolls back cleanly;
+// the lock is released unconditionally once Apply returns.
+func (r *Runner) Apply(ctx context.Context, exec Executor, fullMigrationsTableName string, tableName string) (err error) {
+	if _, lockErr := exec.ExecContext(ctx, AdvisoryLockTemplate, tableName); lockErr != nil {
+		return errorj.CreateTableError.Wrap(lockErr, "failed to acquire migration advisory lock").
+			WithProperty(errorj.DBInfo, nil)
+	}
+	defer func() {
+		_, _ = exec.ExecContext(ctx, AdvisoryUnlockTemplate, tableName)
+	}()
+
+	var maxApplied sql.NullInt64
+	row := exec.QueryRowContext(ctx, fmt.Sprintf(MaxAppliedVersionTemplate, fullMigrationsTableName), tableName)
+	if scanErr := row.Scan(&maxApplied); scanErr != nil && scanErr != sql.ErrNoRows {
+		return errorj.GetTableError.Wrap(scanErr, "failed to read applied migration versions")
+	}
+
+	for _, m := range r.migrations {
+		if m.Table != tableName || m.Version <= maxApplied.Int64 {
+			continue
+		}
+		if err = m.Up(ctx, exec); err != nil {
+			return errorj.PatchTableError.Wrap(err, "failed to apply migration %d for table %s", m.Version, m.Table)
+		}
+		if _, err = exec.ExecContext(ctx, fmt.Sprintf(InsertAppliedTemplate, fullMigrationsTableName), m.Version, m.Table, m.checksum()); err != nil {
+			return errorj.PatchTableError.Wrap(err, "failed to record migration %d for table %s as applied", m.Version, m.Table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package migrations
+
+import "testing"
+
+func TestNewRunnerOrdersMigrationsByVersion(t *testing.T) {
+	r := NewRunner("", Migration{Version: 2, Table: "events"}, Migration{Version: 1, Table: "events"})
+	if len(r.migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(r.migrations))
+	}
+	if r.migrations[0].Version != 1 || r.migrations[1].Version != 2 {
+		t.Fatalf("expected migrations sorted by version, got %+v", r.migrations)
+	}
+	if r.migrationsTable != DefaultMigrationsTable {
+		t.Fatalf("expected default migrations table %q, got %q", DefaultMigrationsTable, r.migrationsTable)
+	}
+}
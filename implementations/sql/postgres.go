@@ -4,14 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jitsucom/bulker/base/errorj"
 	"github.com/jitsucom/bulker/base/logging"
 	"github.com/jitsucom/bulker/base/utils"
 	"github.com/jitsucom/bulker/bulker"
+	"github.com/jitsucom/bulker/implementations/sql/migrations"
 	"github.com/jitsucom/bulker/types"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -26,7 +32,12 @@ func init() {
 const (
 	PostgresBulkerTypeId = "postgres"
 
-	pgTableSchemaQuery = `SELECT 
+	// PostgresDriverPgx is the default driver: jackc/pgx/v5 over a pgxpool.Pool, using binary COPY in LoadTable.
+	PostgresDriverPgx = "pgx"
+	// PostgresDriverPq is the legacy lib/pq driver kept reachable during the pgx migration.
+	PostgresDriverPq = "pq"
+
+	pgTableSchemaQuery = `SELECT
  							pg_attribute.attname AS name,
     						pg_catalog.format_type(pg_attribute.atttypid,pg_attribute.atttypmod) AS column_type
 						FROM pg_attribute
@@ -45,11 +56,14 @@ FROM information_schema.table_constraints tco
               ON kcu.constraint_name = tco.constraint_name
                   AND kcu.constraint_schema = tco.constraint_schema
                   AND kcu.constraint_name = tco.constraint_name
-WHERE tco.constraint_type = 'PRIMARY KEY' AND 
+WHERE tco.constraint_type = 'PRIMARY KEY' AND
       kcu.table_schema = $1 AND
       kcu.table_name = $2`
 	pgCreateDbSchemaIfNotExistsTemplate = `CREATE SCHEMA IF NOT EXISTS "%s"`
 
+	// UpdateSet is built by SQLAdapterBase from the target table's columns, excluding whatever
+	// SystemColumnsFromContext(ctx).CreatedAt names, so a retried/conflicting row keeps its original
+	// creation time while every other column (including UpdatedAt) is refreshed from EXCLUDED.
 	pgMergeQuery = `INSERT INTO {{.TableName}}({{.Columns}}) VALUES ({{.Placeholders}}) ON CONFLICT ON CONSTRAINT {{.PrimaryKeyName}} DO UPDATE set {{.UpdateSet}}`
 
 	pgCopyTemplate = `COPY %s(%s) FROM STDIN`
@@ -63,18 +77,35 @@ var (
 	pgBulkMergeQueryTemplate, _ = template.New("postgresBulkMergeQuery").Parse(pgBulkMergeQuery)
 
 	SchemaToPostgres = map[types.DataType]string{
-		types.STRING:    "text",
-		types.INT64:     "bigint",
-		types.FLOAT64:   "double precision",
-		types.TIMESTAMP: "timestamp",
-		types.BOOL:      "boolean",
-		types.UNKNOWN:   "text",
+		types.STRING:        "text",
+		types.INT64:         "bigint",
+		types.FLOAT64:       "double precision",
+		types.TIMESTAMP:     "timestamp",
+		types.BOOL:          "boolean",
+		types.UNKNOWN:       "text",
+		types.JSON:          "jsonb",
+		types.ARRAY_STRING:  "text[]",
+		types.ARRAY_INT64:   "bigint[]",
+		types.ARRAY_FLOAT64: "double precision[]",
+		types.UUID:          "uuid",
+		types.DECIMAL:       "numeric(38,9)",
+		types.TIMESTAMPTZ:   "timestamptz",
+		types.HSTORE:        "hstore",
 	}
 )
 
 // Postgres is adapter for creating,patching (schema or table), inserting data to postgres
 type Postgres struct {
 	SQLAdapterBase[DataSourceConfig]
+	pool       *pgxpool.Pool
+	driver     string
+	migrations *migrations.Runner
+
+	// Postgres has no GetTableSchema cache, unlike BigQuery/ClickHouse's schemaCache: CreateTable and
+	// PatchTableSchema aren't adapter-owned methods here (they come from the embedded SQLAdapterBase), so
+	// there's nowhere in this adapter to hook a cache-invalidation call. Caching GetTableSchema without one
+	// would let a PatchTableSchema-driven schema migration go unseen by subsequent GetTableSchema callers
+	// on this adapter instance, so this adapter reads the table schema fresh on every call instead.
 }
 
 // NewPostgres return configured Postgres bulker.Bulker instance
@@ -84,25 +115,18 @@ func NewPostgres(bulkerConfig bulker.Config) (bulker.Bulker, error) {
 		return nil, fmt.Errorf("failed to parse destination config: %w", err)
 	}
 
+	driver := config.Driver
+	if driver == "" {
+		driver = PostgresDriverPgx
+	}
+
 	connectionString := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s ",
 		config.Host, config.Port, config.Db, config.Username, config.Password)
-	logging.Infof("connecting: %s", connectionString)
+	logging.Infof("connecting (%s driver): %s", driver, connectionString)
 	//concat provided connection parameters
 	for k, v := range config.Parameters {
 		connectionString += k + "=" + v + " "
 	}
-	dataSource, err := sql.Open("postgres", connectionString)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := dataSource.Ping(); err != nil {
-		_ = dataSource.Close()
-		return nil, err
-	}
-
-	//set default value
-	dataSource.SetConnMaxLifetime(10 * time.Minute)
 
 	tableNameFunc := func(config *DataSourceConfig, tableName string) string {
 		return fmt.Sprintf(`"%s"."%s"`, config.Schema, tableName)
@@ -122,11 +146,73 @@ func NewPostgres(bulkerConfig bulker.Config) (bulker.Bulker, error) {
 				}
 			}
 		}
+		//jsonb/array/hstore columns need a driver-friendly literal rather than the raw Go value
+		switch sqlColumn.Type {
+		case "jsonb":
+			if _, ok := value.(string); !ok && value != nil {
+				if b, err := json.Marshal(value); err == nil {
+					value = string(b)
+				}
+			}
+		case "text[]", "bigint[]", "double precision[]":
+			value = pgArrayLiteral(value)
+		case "hstore":
+			if m, ok := value.(map[string]string); ok {
+				value = hstoreLiteral(m)
+			}
+		case "timestamptz":
+			if v, ok := value.(string); ok {
+				if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+					value = t
+				}
+			}
+		}
 		return value
 	}
 	queryLogger := logging.NewQueryLogger(bulkerConfig.Id, os.Stderr, os.Stderr)
-	p := &Postgres{newSQLAdapterBase(PostgresBulkerTypeId, config, dataSource,
-		queryLogger, typecastFunc, IndexParameterPlaceholder, tableNameFunc, originalColumnName, pgColumnDDL, valueMappingFunc, checkErr)}
+
+	var dataSource *sql.DB
+	var pool *pgxpool.Pool
+	if driver == PostgresDriverPq {
+		dataSource, err := sql.Open("postgres", connectionString)
+		if err != nil {
+			return nil, err
+		}
+		if err := dataSource.Ping(); err != nil {
+			_ = dataSource.Close()
+			return nil, err
+		}
+		p := &Postgres{
+			SQLAdapterBase: newSQLAdapterBase(PostgresBulkerTypeId, config, dataSource,
+				queryLogger, typecastFunc, IndexParameterPlaceholder, tableNameFunc, originalColumnName, pgColumnDDL, valueMappingFunc, checkErr),
+			driver: driver,
+		}
+		return p, nil
+	}
+
+	ctx := context.Background()
+	poolConfig, err := pgxpool.ParseConfig(connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pgx pool config: %w", err)
+	}
+	pool, err = pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	//stdlib.OpenDBFromPool lets the rest of SQLAdapterBase keep using database/sql while LoadTable
+	//reaches into the pool directly for binary CopyFrom.
+	dataSource = stdlib.OpenDBFromPool(pool)
+
+	p := &Postgres{
+		SQLAdapterBase: newSQLAdapterBase(PostgresBulkerTypeId, config, dataSource,
+			queryLogger, typecastFunc, IndexParameterPlaceholder, tableNameFunc, originalColumnName, pgColumnDDL, valueMappingFunc, checkErr),
+		pool:   pool,
+		driver: driver,
+	}
 
 	return p, nil
 }
@@ -137,6 +223,9 @@ func (p *Postgres) CreateStream(id, tableName string, mode bulker.BulkMode, stre
 	if err := p.validateOptions(streamOptions); err != nil {
 		return nil, err
 	}
+	if err := p.applyMigrations(context.Background(), tableName); err != nil {
+		return nil, err
+	}
 	switch mode {
 	case bulker.AutoCommit:
 		return newAutoCommitStream(id, p, tableName, streamOptions...)
@@ -179,9 +268,54 @@ func (p *Postgres) InitDatabase(ctx context.Context) error {
 			})
 	}
 
+	migrationsQuery := fmt.Sprintf(migrations.CreateMigrationsTableTemplate, p.migrationsTableName())
+	if _, err := p.txOrDb(ctx).ExecContext(ctx, migrationsQuery); err != nil {
+		return errorj.CreateSchemaError.Wrap(err, "failed to create schema migrations tracking table").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Schema:    p.config.Schema,
+				Statement: migrationsQuery,
+			})
+	}
+
 	return nil
 }
 
+// migrationsTableName returns the schema-qualified name of the migrations tracking table.
+func (p *Postgres) migrationsTableName() string {
+	return p.fullTableName(migrations.DefaultMigrationsTable)
+}
+
+// SetMigrations registers the ordered set of schema migrations CreateStream will apply (once per
+// table, guarded by a Postgres advisory lock) before handing back the stream.
+func (p *Postgres) SetMigrations(ms ...migrations.Migration) {
+	p.migrations = migrations.NewRunner(p.migrationsTableName(), ms...)
+}
+
+// applyMigrations runs any pending migrations for tableName inside a transaction, acquiring the
+// Postgres advisory lock for tableName so concurrent bulker instances don't race on the same table.
+func (p *Postgres) applyMigrations(ctx context.Context, tableName string) (err error) {
+	if p.migrations == nil {
+		return nil
+	}
+	tx, err := p.dataSource.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = p.migrations.Apply(ctx, tx, p.migrationsTableName(), tableName); err != nil {
+		return errorj.PatchTableError.Wrap(err, "failed to apply schema migrations").
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Schema: p.config.Schema,
+				Table:  tableName,
+			})
+	}
+	return tx.Commit()
+}
+
 // GetTableSchema returns table (name,columns with name and types) representation wrapped in Table struct
 func (p *Postgres) GetTableSchema(ctx context.Context, tableName string) (*Table, error) {
 	table, err := p.getTable(ctx, tableName)
@@ -257,22 +391,80 @@ func (p *Postgres) getTable(ctx context.Context, tableName string) (*Table, erro
 	return table, nil
 }
 
-func (p *Postgres) Insert(ctx context.Context, table *Table, merge bool, objects []types.Object) error {
-	if !merge {
-		return p.insert(ctx, table, objects)
-	} else {
+func (p *Postgres) Insert(ctx context.Context, table *Table, merge bool, objects []types.Object) (err error) {
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeInsert != nil {
+		if objects, err = hooks.BeforeInsert(ctx, table, objects); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		runOnError(ctx, hooks, "insert", err)
+		if err == nil && hooks.AfterInsert != nil {
+			hooks.AfterInsert(ctx, table, InsertStats{Objects: len(objects)})
+		}
+	}()
+	// merge (ON CONFLICT DO UPDATE) is idempotent regardless of attempt count; a plain insert is only
+	// idempotent under AutoCommit/ReplaceTable modes, where a retried batch lands in a table that gets
+	// truncated/replaced rather than accumulating duplicate rows.
+	mode := ModeFromContext(ctx)
+	idempotent := merge || mode == bulker.AutoCommit || mode == bulker.ReplaceTable
+	attempts := 0
+	err = withRetry(ctx, idempotent, func(attempt int) error {
+		attempts = attempt
+		if !merge {
+			return p.insert(ctx, table, objects)
+		}
 		return p.insertOrMerge(ctx, table, objects, pgMergeQueryTemplate)
+	})
+	if err != nil {
+		err = errorj.ExecuteInsertInBatchError.Wrap(err, "failed to insert into table after %d attempt(s)", attempts).
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Schema: p.config.Schema,
+				Table:  table.Name,
+			})
 	}
+	return err
 }
 
-func (p *Postgres) CopyTables(ctx context.Context, targetTable *Table, sourceTable *Table, merge bool) error {
-	if !merge {
-		return p.copy(ctx, targetTable, sourceTable)
-	} else {
+func (p *Postgres) CopyTables(ctx context.Context, targetTable *Table, sourceTable *Table, merge bool) (err error) {
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeCopyTables != nil {
+		if err = hooks.BeforeCopyTables(ctx, targetTable, sourceTable); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		runOnError(ctx, hooks, "copyTables", err)
+		if err == nil && hooks.AfterCopyTables != nil {
+			hooks.AfterCopyTables(ctx, targetTable, sourceTable)
+		}
+	}()
+	// merge is idempotent regardless of retries; a plain copy is only safe to retry under
+	// ReplaceTable/ReplacePartition, where the destination is truncated/swapped before the copy runs.
+	mode := ModeFromContext(ctx)
+	idempotent := merge || mode == bulker.ReplaceTable || mode == bulker.ReplacePartition
+	attempts := 0
+	err = withRetry(ctx, idempotent, func(attempt int) error {
+		attempts = attempt
+		if !merge {
+			return p.copy(ctx, targetTable, sourceTable)
+		}
 		return p.copyOrMerge(ctx, targetTable, sourceTable, pgBulkMergeQueryTemplate, pgBulkMergeSourceAlias)
+	})
+	if err != nil {
+		err = errorj.CopyError.Wrap(err, "failed to copy table after %d attempt(s)", attempts).
+			WithProperty(errorj.DBInfo, &types.ErrorPayload{
+				Schema: p.config.Schema,
+				Table:  targetTable.Name,
+			})
 	}
+	return err
 }
 
+// LoadTable streams loadSource's CSV rows into targetTable using Postgres' binary COPY protocol (pgx.CopyFrom).
+// When the adapter was opened with the legacy PostgresDriverPq driver, it falls back to a prepared
+// `COPY ... FROM STDIN` statement executed row by row.
 func (p *Postgres) LoadTable(ctx context.Context, targetTable *Table, loadSource *LoadSource) (err error) {
 	if loadSource.Type != LocalFile {
 		return fmt.Errorf("LoadTable: only local file is supported")
@@ -280,34 +472,85 @@ func (p *Postgres) LoadTable(ctx context.Context, targetTable *Table, loadSource
 	if loadSource.Format != CSV {
 		return fmt.Errorf("LoadTable: only CSV format is supported")
 	}
-	var headerWithQuotes []string
-	for _, name := range targetTable.SortedColumnNames() {
-		headerWithQuotes = append(headerWithQuotes, fmt.Sprintf(`"%s"`, name))
+	hooks := HooksFromContext(ctx)
+	if hooks.BeforeLoadTable != nil {
+		if err = hooks.BeforeLoadTable(ctx, targetTable, loadSource); err != nil {
+			return err
+		}
 	}
-	copyStatement := fmt.Sprintf(pgCopyTemplate, p.fullTableName(targetTable.Name), strings.Join(headerWithQuotes, ", "))
+	columnNames := targetTable.SortedColumnNames()
+	copyStatement := fmt.Sprintf(pgCopyTemplate, p.fullTableName(targetTable.Name), strings.Join(quoteColumns(columnNames), ", "))
+	attempts := 0
 	defer func() {
+		runOnError(ctx, hooks, "loadTable", err)
 		if err != nil {
-			err = errorj.LoadError.Wrap(err, "failed to load table").
+			err = errorj.LoadError.Wrap(err, "failed to load table after %d attempt(s)", attempts).
 				WithProperty(errorj.DBInfo, &types.ErrorPayload{
 					Schema:      p.config.Schema,
 					Table:       targetTable.Name,
 					PrimaryKeys: targetTable.GetPKFields(),
 					Statement:   copyStatement,
 				})
+		} else if hooks.AfterLoadTable != nil {
+			hooks.AfterLoadTable(ctx, targetTable)
 		}
 	}()
 
+	// A load replaces/truncates the destination under ReplaceTable/ReplacePartition, so a retried
+	// attempt can't leave duplicate rows behind; other modes only retry on connection-establishment errors.
+	mode := ModeFromContext(ctx)
+	idempotent := mode == bulker.ReplaceTable || mode == bulker.ReplacePartition
+
+	return withRetry(ctx, idempotent, func(attempt int) error {
+		attempts = attempt
+		if p.driver == PostgresDriverPq {
+			return p.loadTableTextCopy(ctx, targetTable, loadSource, copyStatement)
+		}
+
+		file, err := os.Open(loadSource.Path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		reader := csv.NewReader(file)
+		header, err := reader.Read() //header
+		if err != nil {
+			return err
+		}
+		source := &csvCopyFromSource{reader: reader, header: header, columns: targetTable.Columns}
+
+		conn, err := p.pool.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Release()
+
+		identifier := pgx.Identifier{p.config.Schema, targetTable.Name}
+		_, err = conn.Conn().CopyFrom(ctx, identifier, columnNames, source)
+		if err != nil {
+			return checkErr(err)
+		}
+		if source.err != nil && source.err != io.EOF {
+			return checkErr(source.err)
+		}
+		return nil
+	})
+}
+
+// loadTableTextCopy is the legacy text-mode COPY path kept for PostgresDriverPq during the pgx transition.
+func (p *Postgres) loadTableTextCopy(ctx context.Context, targetTable *Table, loadSource *LoadSource, copyStatement string) error {
 	stmt, err := p.txOrDb(ctx).PrepareContext(ctx, copyStatement)
 	if err != nil {
 		return err
 	}
-	//f, err := os.ReadFile(loadSource.Path)
-	//logging.Infof("FILE: %s", f)
 
 	file, err := os.Open(loadSource.Path)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
+
 	reader := csv.NewReader(file)
 	_, _ = reader.Read() //skip header
 	for {
@@ -338,6 +581,120 @@ func (p *Postgres) LoadTable(ctx context.Context, targetTable *Table, loadSource
 	return checkErr(stmt.Close())
 }
 
+// csvCopyFromSource adapts a CSV batch file to pgx.CopyFromSource, typing each value according to the
+// destination column's SQLColumn.Type so rows go over the wire in binary form instead of as strings.
+type csvCopyFromSource struct {
+	reader  *csv.Reader
+	header  []string
+	columns map[string]SQLColumn
+	current []any
+	err     error
+}
+
+func (s *csvCopyFromSource) Next() bool {
+	record, err := s.reader.Read()
+	if err != nil {
+		if err != io.EOF {
+			s.err = err
+		}
+		return false
+	}
+	values := make([]any, len(record))
+	for i, raw := range record {
+		if raw == "\\N" {
+			values[i] = nil
+			continue
+		}
+		var column SQLColumn
+		if i < len(s.header) {
+			column = s.columns[s.header[i]]
+		}
+		values[i] = castCopyValue(raw, column)
+	}
+	s.current = values
+	return true
+}
+
+func (s *csvCopyFromSource) Values() ([]any, error) {
+	return s.current, s.err
+}
+
+func (s *csvCopyFromSource) Err() error {
+	return s.err
+}
+
+// castCopyValue converts a raw CSV cell into a typed Go value matching column's Postgres type so pgx
+// can encode it in binary rather than falling back to text encoding.
+func castCopyValue(raw string, column SQLColumn) any {
+	colType := strings.ToLower(column.Type)
+	switch {
+	case colType == "bigint", colType == "integer", colType == "smallint":
+		if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return v
+		}
+	case colType == "double precision", colType == "real", colType == "decimal", strings.HasPrefix(colType, "numeric"):
+		if v, err := strconv.ParseFloat(raw, 64); err == nil {
+			return v
+		}
+	case colType == "boolean":
+		if v, err := strconv.ParseBool(raw); err == nil {
+			return v
+		}
+	case colType == "timestamp", colType == "timestamptz", colType == "timestamp with time zone", colType == "timestamp without time zone":
+		if v, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			return v
+		}
+	}
+	return raw
+}
+
+// pgArrayLiteral renders a []string/[]int64/[]float64 as a Postgres array wire-format literal, e.g.
+// `{a,b,c}`. Values of any other shape are returned unchanged and left to the driver's default encoding.
+func pgArrayLiteral(value any) any {
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+	}
+	switch v := value.(type) {
+	case []string:
+		elems := make([]string, len(v))
+		for i, s := range v {
+			elems[i] = quote(s)
+		}
+		return "{" + strings.Join(elems, ",") + "}"
+	case []int64:
+		elems := make([]string, len(v))
+		for i, n := range v {
+			elems[i] = strconv.FormatInt(n, 10)
+		}
+		return "{" + strings.Join(elems, ",") + "}"
+	case []float64:
+		elems := make([]string, len(v))
+		for i, f := range v {
+			elems[i] = strconv.FormatFloat(f, 'g', -1, 64)
+		}
+		return "{" + strings.Join(elems, ",") + "}"
+	}
+	return value
+}
+
+// hstoreLiteral renders m as a Postgres hstore text literal, e.g. `"a"=>"1", "b"=>"2"`.
+func hstoreLiteral(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, fmt.Sprintf(`"%s"=>"%s"`,
+			strings.ReplaceAll(k, `"`, `\"`), strings.ReplaceAll(v, `"`, `\"`)))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func quoteColumns(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf(`"%s"`, name)
+	}
+	return quoted
+}
+
 // pgColumnDDL returns column DDL (quoted column name, mapped sql type and 'not null' if pk field)
 func pgColumnDDL(name string, column SQLColumn, pkFields utils.Set[string]) string {
 	var notNullClause string
@@ -354,7 +711,18 @@ func pgColumnDDL(name string, column SQLColumn, pkFields utils.Set[string]) stri
 // return default value statement for creating column
 func getDefaultValueStatement(sqlType string) string {
 	//get default value based on type
-	if strings.Contains(sqlType, "var") || strings.Contains(sqlType, "text") {
+	switch {
+	case strings.HasSuffix(sqlType, "[]"):
+		return "default ARRAY[]::" + sqlType
+	case sqlType == "jsonb":
+		return "default '{}'::jsonb"
+	case sqlType == "hstore":
+		return "default ''::hstore"
+	case sqlType == "uuid":
+		return "default '00000000-0000-0000-0000-000000000000'::uuid"
+	case strings.HasPrefix(sqlType, "numeric"):
+		return "default 0::numeric"
+	case strings.Contains(sqlType, "var") || strings.Contains(sqlType, "text"):
 		return "default ''"
 	}
 
@@ -0,0 +1,133 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	retryBaseDelay   = 250 * time.Millisecond
+	retryCapDelay    = 30 * time.Second
+	retryMaxAttempts = 5
+)
+
+// retryablePgSQLStates are Postgres SQLSTATE codes worth retrying: connection loss/refusal (08006,
+// 08003, 08000), serialization failures (40001), deadlocks (40P01), and admin shutdown (57P01).
+var retryablePgSQLStates = map[string]bool{
+	"08006": true,
+	"08003": true,
+	"08000": true,
+	"40001": true,
+	"40P01": true,
+	"57P01": true,
+}
+
+// retryableGoogleAPIStatus are HTTP statuses BigQuery/Google APIs return for transient backend
+// trouble: 429 (rateLimitExceeded/quotaExceeded), 500/503 (backendError/internalError), 504 (timeout).
+var retryableGoogleAPIStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// retryableBQReasons are the per-row error reasons bigquery.PutMultiError carries for the legacy
+// streaming Inserter; anything else (e.g. "invalid") is a permanent rejection of that row's data.
+var retryableBQReasons = map[string]bool{
+	"backendError":      true,
+	"internalError":     true,
+	"rateLimitExceeded": true,
+}
+
+// isRetryable classifies err as transient and safe to retry. It recognizes the underlying Postgres
+// SQLSTATE (via pgconn.PgError), a googleapi.Error with a transient HTTP status (BigQuery jobs and
+// queries surface failures this way), a bigquery.PutMultiError whose rows were all rejected for a
+// transient reason, a context deadline exceeded mid-job.Wait, or a raw network error.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return retryablePgSQLStates[pgErr.Code]
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return retryableGoogleAPIStatus[apiErr.Code]
+	}
+	var multiErr bigquery.PutMultiError
+	if errors.As(err, &multiErr) {
+		for _, rowErr := range multiErr {
+			for _, e := range rowErr.Errors {
+				if !retryableBQReasons[e.Reason] {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr *net.OpError
+	return errors.As(err, &netErr)
+}
+
+// withRetry runs op with exponential backoff and jitter (base 250ms, cap 30s, max 5 attempts).
+// When idempotent is false, op is only retried for errors classified by connectErrOnly as having
+// failed before any bytes were written (i.e. connection establishment), so a non-idempotent write
+// is never silently replayed against the destination.
+func withRetry(ctx context.Context, idempotent bool, op func(attempt int) error) (err error) {
+	delay := retryBaseDelay
+	for attempt := 1; attempt <= retryMaxAttempts; attempt++ {
+		err = op(attempt)
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) {
+			return err
+		}
+		if !idempotent && !isConnectionEstablishmentError(err) {
+			return err
+		}
+		if attempt == retryMaxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(jitter(delay)):
+		}
+		delay *= 2
+		if delay > retryCapDelay {
+			delay = retryCapDelay
+		}
+	}
+	return err
+}
+
+// isConnectionEstablishmentError reports whether err happened while opening the connection (and
+// therefore before any bytes of the operation were written), making it safe to retry even
+// non-idempotent operations.
+func isConnectionEstablishmentError(err error) bool {
+	var netErr *net.OpError
+	return errors.As(err, &netErr) && netErr.Op == "dial"
+}
+
+// jitter returns a random duration in [d/2, d) to avoid synchronized retry storms.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
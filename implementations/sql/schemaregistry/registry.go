@@ -0,0 +1,107 @@
+// Package schemaregistry is a minimal Confluent Schema Registry client: given a payload framed in
+// Confluent's wire format (a 0x00 magic byte, a 4-byte big-endian schema id, then the Avro/JSON body),
+// it resolves the registered schema for that id and caches it in an LRU so repeat events sharing a
+// schema cost exactly one registry round trip, not one per event.
+package schemaregistry
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// MagicByte is the first byte of every Confluent-framed payload, identifying the wire format version.
+const MagicByte = 0x00
+
+// WireHeaderLen is the length, in bytes, of the magic byte + schema id prefix every Confluent-framed
+// payload carries ahead of its Avro/JSON body.
+const WireHeaderLen = 5
+
+// DefaultCacheSize is how many (subject, id) schema entries a Client keeps before evicting the least
+// recently used — generous enough that a pipeline with a handful of evolving subjects never thrashes.
+const DefaultCacheSize = 1024
+
+// Schema is a single Schema Registry entry: its raw definition (Avro or JSON Schema text, whichever the
+// subject uses) plus the id and subject it was looked up under.
+type Schema struct {
+	ID      int
+	Subject string
+	Raw     string
+}
+
+type cacheKey struct {
+	subject string
+	id      int
+}
+
+// Client looks up schemas from a Schema Registry instance, caching each by (subject, id) so a stable
+// upstream schema is fetched at most once no matter how many events reference it.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	cache      *lru.Cache[cacheKey, *Schema]
+}
+
+// NewClient returns a Client against a Schema Registry reachable at baseURL (e.g.
+// "http://localhost:8081"), with an LRU cache of DefaultCacheSize entries.
+func NewClient(baseURL string) (*Client, error) {
+	cache, err := lru.New[cacheKey, *Schema](DefaultCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: http.DefaultClient, cache: cache}, nil
+}
+
+// DecodeWireHeader extracts the schema id Confluent's wire format embeds ahead of a payload's Avro/JSON
+// body, returning the remaining bytes (the encoded record itself). It errors if payload is shorter than
+// WireHeaderLen or doesn't start with MagicByte.
+func DecodeWireHeader(payload []byte) (schemaID int, body []byte, err error) {
+	if len(payload) < WireHeaderLen {
+		return 0, nil, fmt.Errorf("schemaregistry: payload too short for Confluent wire format (%d bytes)", len(payload))
+	}
+	if payload[0] != MagicByte {
+		return 0, nil, fmt.Errorf("schemaregistry: unexpected magic byte 0x%02x", payload[0])
+	}
+	id := int(binary.BigEndian.Uint32(payload[1:5]))
+	return id, payload[WireHeaderLen:], nil
+}
+
+// Lookup returns the schema registered under id, from cache when present, otherwise fetched from the
+// registry's GET /schemas/ids/{id} endpoint and cached for subsequent calls. subject only scopes the
+// cache key — Schema Registry's /schemas/ids endpoint itself is subject-agnostic — so two subjects that
+// happen to share a schema id each still get their own cache entry instead of racing.
+func (c *Client) Lookup(ctx context.Context, subject string, id int) (*Schema, error) {
+	key := cacheKey{subject: subject, id: id}
+	if s, ok := c.cache.Get(key); ok {
+		return s, nil
+	}
+	u := fmt.Sprintf("%s/schemas/ids/%d", c.baseURL, id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("schemaregistry: GET %s failed with status %s: %s", u, resp.Status, string(respBody))
+	}
+	var decoded struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("schemaregistry: decoding response from %s: %w", u, err)
+	}
+	schema := &Schema{ID: id, Subject: subject, Raw: decoded.Schema}
+	c.cache.Add(key, schema)
+	return schema, nil
+}
@@ -0,0 +1,65 @@
+package sql
+
+import (
+	"sync/atomic"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// tableSchemaCacheSize is a schemaCache's default capacity. The request behind this cache (see
+// [[chunk6-3]]) asked for ~10k entries at TableHelper scope; an adapter instance already lives for the
+// lifetime of a bulker.Bulker and is shared by every stream it opens, so the same default applies here.
+const tableSchemaCacheSize = 10000
+
+// tableSchemaCache caches GetTableSchema results per adapter instance (BigQuery/ClickHouse today; see
+// each adapter's schemaCache field), keyed by table name, so repeated GetTableSchema calls across many
+// streams on the same destination skip a round trip to the warehouse's information_schema once a table's
+// shape is known. CreateTable and PatchTableSchema — the two operations that can change what
+// GetTableSchema would return — invalidate the corresponding entry.
+//
+// This complements tableMappingCache (see table_cache.go), which caches MapTableSchema's per-stream,
+// per-signature *Table results; this cache instead holds the existing-table schema GetTableSchema itself
+// reads off the destination, and lives for the adapter's lifetime rather than one stream's.
+type tableSchemaCache struct {
+	cache *lru.Cache[string, *Table]
+	hits  uint64
+	misses uint64
+}
+
+// newTableSchemaCache returns a tableSchemaCache holding up to size entries (0 or negative falls back to
+// tableSchemaCacheSize).
+func newTableSchemaCache(size int) *tableSchemaCache {
+	if size <= 0 {
+		size = tableSchemaCacheSize
+	}
+	c, _ := lru.New[string, *Table](size)
+	return &tableSchemaCache{cache: c}
+}
+
+// Get returns the cached *Table for tableName, if present, recording a hit or miss.
+func (c *tableSchemaCache) Get(tableName string) (*Table, bool) {
+	table, ok := c.cache.Get(tableName)
+	if ok {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+	return table, ok
+}
+
+// Put stores table under tableName for subsequent Get calls.
+func (c *tableSchemaCache) Put(tableName string, table *Table) {
+	c.cache.Add(tableName, table)
+}
+
+// Invalidate drops tableName's cached schema, if any, so the next Get is a miss. Call this from
+// CreateTable/PatchTableSchema (or any other DDL that changes tableName's shape).
+func (c *tableSchemaCache) Invalidate(tableName string) {
+	c.cache.Remove(tableName)
+}
+
+// Hits returns how many Get calls found a cached entry.
+func (c *tableSchemaCache) Hits() uint64 { return atomic.LoadUint64(&c.hits) }
+
+// Misses returns how many Get calls found nothing cached.
+func (c *tableSchemaCache) Misses() uint64 { return atomic.LoadUint64(&c.misses) }